@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/mailer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultDeletionGracePeriod = 30 * 24 * time.Hour
+
+// DeletionGracePeriod is how long a user can cancel the deletion of their
+// account, configured with ACCOUNT_DELETION_GRACE_PERIOD (default 720h).
+func DeletionGracePeriod() time.Duration {
+	return durationFromEnv("ACCOUNT_DELETION_GRACE_PERIOD", defaultDeletionGracePeriod)
+}
+
+// ScheduleAccountDeletion marks the account for deletion once the grace
+// period is over and signs the user out everywhere.
+func ScheduleAccountDeletion(user models.User, mode string) (time.Time, error) {
+	scheduledAt := time.Now().Add(DeletionGracePeriod())
+	err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).UpdateColumns(map[string]interface{}{
+			"deletion_scheduled_at": scheduledAt,
+			"deletion_mode":         mode,
+		}).Error; err != nil {
+			return err
+		}
+		if err := revokeSessions(tx, "user_id = ?", user.ID); err != nil {
+			return err
+		}
+		return revokePersonalAccessTokens(tx, user.ID)
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	notifyDeletionScheduled(user, scheduledAt)
+	return scheduledAt, nil
+}
+
+// CancelAccountDeletion keeps the account if it has not been purged yet.
+func CancelAccountDeletion(userID uint) error {
+	return initializer.DB.Model(&models.User{}).Where("id = ?", userID).UpdateColumns(map[string]interface{}{
+		"deletion_scheduled_at": nil,
+		"deletion_mode":         "",
+	}).Error
+}
+
+// StartAccountPurger deletes the accounts whose grace period is over every
+// interval. It blocks, so run it in its own goroutine.
+func StartAccountPurger(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := PurgeDueAccounts(); err != nil {
+			log.Printf("Failed to purge deleted accounts: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// PurgeDueAccounts deletes every account whose deletion is due. Rows are
+// locked with SKIP LOCKED so several replicas can run the purger at once.
+func PurgeDueAccounts() error {
+	for {
+		purged := false
+		err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+			var user models.User
+			result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("deletion_scheduled_at <= ?", time.Now()).
+				Limit(1).
+				Find(&user)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			purged = true
+			return purgeAccount(tx, user)
+		})
+		if err != nil || !purged {
+			return err
+		}
+	}
+}
+
+// purgeAccount removes everything that identifies the user and soft deletes
+// the account. In cascade mode the user's posts, comments and likes are soft
+// deleted as well and the revisions of the posts are removed; otherwise they
+// stay and point at the anonymized account.
+func purgeAccount(tx *gorm.DB, user models.User) error {
+	if user.DeletionMode == models.DeletionModeCascade {
+		postIDs := tx.Model(&models.Post{}).Select("id").Where("user_id = ?", user.ID)
+		steps := []*gorm.DB{
+			tx.Where("post_id IN (?)", postIDs).Delete(&models.Comment{}),
+			tx.Where("post_id IN (?)", postIDs).Delete(&models.Like{}),
+			tx.Where("post_id IN (?)", postIDs).Delete(&models.Media{}),
+			// Revisions are full copies, also of posts the user deleted before
+			tx.Where("post_id IN (?)", tx.Unscoped().Model(&models.Post{}).Select("id").Where("user_id = ?", user.ID)).Delete(&models.PostRevision{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.Post{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.Comment{}),
+			tx.Where("user_id = ?", user.ID).Delete(&models.Like{}),
+		}
+		for _, step := range steps {
+			if step.Error != nil {
+				return step.Error
+			}
+		}
+	}
+
+	// Credentials and social graph rows are useless without the account
+	steps := []*gorm.DB{
+		tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}),
+		tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.PasswordResetToken{}),
+		tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.RefreshToken{}),
+		tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Session{}),
+		tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.PersonalAccessToken{}),
+		tx.Where("follower_id = ? OR followee_id = ?", user.ID, user.ID).Delete(&models.Follow{}),
+		tx.Where("blocker_id = ? OR blocked_id = ?", user.ID, user.ID).Delete(&models.Block{}),
+		tx.Where("muter_id = ? OR muted_id = ?", user.ID, user.ID).Delete(&models.Mute{}),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+
+	// The auth event log and invitations tied to the address keep no trace
+	// of who the user was
+	steps = []*gorm.DB{
+		tx.Unscoped().Model(&models.AuthEvent{}).
+			Where("user_id = ? OR lower(email) = lower(?)", user.ID, user.Email).
+			UpdateColumns(map[string]interface{}{"email": "", "ip": "", "user_agent": ""}),
+		tx.Unscoped().Model(&models.Invitation{}).
+			Where("lower(email) IN (lower(?), lower(?))", user.Email, user.PendingEmail).
+			UpdateColumn("email", ""),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+
+	// Free the username and email for new sign ups; the row itself is kept
+	// soft deleted so that anonymized content still has an author ID.
+	if err := tx.Model(&user).UpdateColumns(map[string]interface{}{
+		"username":              fmt.Sprintf("deleted-user-%d", user.ID),
+		"email":                 fmt.Sprintf("deleted-user-%d@deleted.invalid", user.ID),
+		"password":              "",
+		"bio":                   "",
+		"profile_pic":           "",
+		"pending_email":         "",
+		"email_verified_at":     nil,
+		"totp_secret":           "",
+		"totp_enabled":          false,
+		"deletion_scheduled_at": nil,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&user).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.AuthEvent{
+		UserID: &user.ID,
+		Event:  models.AuthEventAccountDeleted,
+		Reason: user.DeletionMode,
+	}).Error
+}
+
+// notifyDeletionScheduled tells the user when their account will be deleted
+// and how to keep it.
+func notifyDeletionScheduled(user models.User, scheduledAt time.Time) {
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your account is scheduled for deletion",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account will be deleted on %s.\n\nIf you want to keep it, log in before then and cancel the deletion: %s/account\n",
+			user.Username, scheduledAt.Format(time.RFC1123), os.Getenv("APP_URL")),
+	}
+	go func() {
+		if err := initializer.Mailer.Send(msg); err != nil {
+			log.Printf("Failed to send account deletion email: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,16 @@
+package auth
+
+import (
+	"log"
+
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+)
+
+// RecordAuditLog writes an entry to the impersonation audit log. Failures are
+// only logged because the request has already been handled.
+func RecordAuditLog(entry models.AuditLog) {
+	if err := initializer.DB.Create(&entry).Error; err != nil {
+		log.Printf("Failed to record audit log for %s %s: %v", entry.Method, entry.Path, err)
+	}
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Registration modes, configured with REGISTRATION_MODE.
+const (
+	RegistrationOpen       = "open"        // Anyone can sign up
+	RegistrationInviteOnly = "invite_only" // Signing up requires an invitation code
+	RegistrationClosed     = "closed"      // Nobody can sign up
+)
+
+// ErrInvalidInvitation is returned when an invitation code is unknown,
+// revoked, expired, used up or tied to another email address.
+var ErrInvalidInvitation = errors.New("invalid invitation code")
+
+// RegistrationMode returns REGISTRATION_MODE (default "open"). Unknown values
+// close registration rather than opening it by accident.
+func RegistrationMode() string {
+	switch mode := os.Getenv("REGISTRATION_MODE"); mode {
+	case "":
+		return RegistrationOpen
+	case RegistrationOpen, RegistrationInviteOnly, RegistrationClosed:
+		return mode
+	default:
+		log.Printf("Unknown REGISTRATION_MODE %q, registration is closed", mode)
+		return RegistrationClosed
+	}
+}
+
+// GenerateInvitationCode returns a new invitation code formatted as
+// xxxx-xxxx-xxxx-xxxx, its hash and the prefix shown in listings. Codes are
+// normalized like recovery codes before hashing, so case and dashes do not matter.
+func GenerateInvitationCode() (string, string, string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	raw := strings.ToLower(totpEncoding.EncodeToString(buf))
+	code := raw[:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+	return code, HashToken(NormalizeRecoveryCode(code)), code[:4], nil
+}
+
+// ConsumeInvitation checks the invitation code for the email address and
+// counts one use of it. It must run in the transaction that creates the
+// user so that a failed sign up does not use up the code.
+func ConsumeInvitation(tx *gorm.DB, code, email string) (models.Invitation, error) {
+	var invitation models.Invitation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", HashToken(NormalizeRecoveryCode(code)), time.Now()).
+		First(&invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invitation, ErrInvalidInvitation
+		}
+		return invitation, err
+	}
+	if invitation.UseCount >= invitation.MaxUses {
+		return invitation, ErrInvalidInvitation
+	}
+	if invitation.Email != "" && !strings.EqualFold(invitation.Email, email) {
+		return invitation, ErrInvalidInvitation
+	}
+
+	invitation.UseCount++
+	if err := tx.Model(&invitation).UpdateColumn("use_count", invitation.UseCount).Error; err != nil {
+		return invitation, err
+	}
+	return invitation, nil
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/mailer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultMaxLoginAttempts   = 5
+	defaultLockoutBase        = 5 * time.Minute
+	defaultLockoutMax         = 24 * time.Hour
+	defaultMaxIPLoginAttempts = 20
+	defaultIPLoginWindow      = 15 * time.Minute
+)
+
+// RecordAuthEvent writes an entry to the auth event log. Failures are only logged
+// so that a broken event log does not prevent users from logging in.
+func RecordAuthEvent(event models.AuthEvent) {
+	if err := initializer.DB.Create(&event).Error; err != nil {
+		log.Printf("Failed to record auth event %s: %v", event.Event, err)
+	}
+}
+
+// IPThrottled reports whether the IP has made too many failed login attempts
+// within LOGIN_IP_WINDOW (default 15m) and how long it has to wait.
+func IPThrottled(ip string) (bool, time.Duration) {
+	window := durationFromEnv("LOGIN_IP_WINDOW", defaultIPLoginWindow)
+	limit := intFromEnv("LOGIN_IP_MAX_ATTEMPTS", defaultMaxIPLoginAttempts)
+
+	var failures []models.AuthEvent
+	initializer.DB.
+		Where("ip = ? AND event IN ? AND created_at > ?", ip, []string{models.AuthEventLoginFailure, models.AuthEventMFAFailure}, time.Now().Add(-window)).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&failures)
+	if len(failures) < limit {
+		return false, 0
+	}
+
+	// The IP can try again once the oldest of the counted failures leaves the window
+	oldest := failures[len(failures)-1].CreatedAt
+	return true, time.Until(oldest.Add(window))
+}
+
+// IsLocked reports whether the account is temporarily locked.
+func IsLocked(user models.User) bool {
+	return user.LockedUntil != nil && time.Now().Before(*user.LockedUntil)
+}
+
+// RegisterLoginFailure counts a failed login for the account. After
+// LOGIN_MAX_ATTEMPTS (default 5) failures the account is locked. Every
+// consecutive lockout doubles its duration, starting at LOGIN_LOCKOUT_BASE
+// (default 5m) up to LOGIN_LOCKOUT_MAX (default 24h). It reports whether the
+// account has been locked.
+func RegisterLoginFailure(user models.User) bool {
+	var lockedUntil time.Time
+	err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, user.ID).Error; err != nil {
+			return err
+		}
+
+		user.FailedLoginAttempts++
+		if user.FailedLoginAttempts < intFromEnv("LOGIN_MAX_ATTEMPTS", defaultMaxLoginAttempts) {
+			return tx.Model(&user).UpdateColumn("failed_login_attempts", user.FailedLoginAttempts).Error
+		}
+
+		lockedUntil = time.Now().Add(lockoutDuration(user.LockoutCount))
+		return tx.Model(&user).UpdateColumns(map[string]interface{}{
+			"failed_login_attempts": 0,
+			"lockout_count":         user.LockoutCount + 1,
+			"locked_until":          lockedUntil,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("Failed to register login failure: %v", err)
+		return false
+	}
+	if lockedUntil.IsZero() {
+		return false
+	}
+
+	notifyLockout(user, lockedUntil)
+	return true
+}
+
+// ResetLoginFailures clears the failure counters after a successful login.
+func ResetLoginFailures(user models.User) {
+	if user.FailedLoginAttempts == 0 && user.LockoutCount == 0 && user.LockedUntil == nil {
+		return
+	}
+	initializer.DB.Model(&user).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"lockout_count":         0,
+		"locked_until":          nil,
+	})
+}
+
+// UnlockAccount lifts a lockout and resets the backoff.
+func UnlockAccount(userID uint) error {
+	return initializer.DB.Model(&models.User{}).Where("id = ?", userID).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"lockout_count":         0,
+		"locked_until":          nil,
+	}).Error
+}
+
+func lockoutDuration(previousLockouts int) time.Duration {
+	base := durationFromEnv("LOGIN_LOCKOUT_BASE", defaultLockoutBase)
+	max := durationFromEnv("LOGIN_LOCKOUT_MAX", defaultLockoutMax)
+
+	d := base
+	for i := 0; i < previousLockouts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// notifyLockout tells the owner of the account that it has been locked. The
+// login response itself does not reveal the lockout.
+func notifyLockout(user models.User, lockedUntil time.Time) {
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been temporarily locked",
+		Body: fmt.Sprintf("Hi %s,\n\nThere were too many failed attempts to log in to your account, so logins are blocked until %s.\n\nIf this was not you, consider resetting your password: %s/forgot-password\n",
+			user.Username, lockedUntil.Format(time.RFC1123), os.Getenv("APP_URL")),
+	}
+	go func() {
+		if err := initializer.Mailer.Send(msg); err != nil {
+			log.Printf("Failed to send lockout email: %v", err)
+		}
+	}()
+}
+
+func intFromEnv(key string, fallback int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fallback
+}
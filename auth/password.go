@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	defaultPasswordMinLength = 10
+
+	// passwordMaxBytes is the longest password bcrypt accepts.
+	passwordMaxBytes = 72
+
+	// breachedPrefixLength is the length of the SHA-1 prefix the breached
+	// password list is bucketed by, as in the k-anonymity range API of
+	// Have I Been Pwned.
+	breachedPrefixLength = 5
+)
+
+// Password policy violation codes.
+const (
+	PasswordTooShort         = "too_short"
+	PasswordTooLong          = "too_long"
+	PasswordCommon           = "common"
+	PasswordContainsUsername = "contains_username"
+	PasswordContainsEmail    = "contains_email"
+	PasswordBreached         = "breached"
+)
+
+// PasswordViolation describes one way a password breaks the policy.
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a password breaks.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	codes := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		codes[i] = v.Code
+	}
+	return "password violates the policy: " + strings.Join(codes, ", ")
+}
+
+//go:embed data/common-passwords.txt
+var bundledCommonPasswords string
+
+//go:embed data/breached-sha1.txt
+var bundledBreachedPasswords string
+
+var (
+	commonPasswordsOnce sync.Once
+	commonPasswords     map[string]bool
+
+	breachedPasswordsOnce sync.Once
+	breachedPasswords     map[string]map[string]bool // SHA-1 prefix -> suffixes
+)
+
+// ValidatePassword checks a new password against the password policy and
+// returns a *PasswordPolicyError listing every violation, or nil.
+//
+// The policy is configured with PASSWORD_MIN_LENGTH (default 10),
+// PASSWORD_BLOCK_COMMON (default true) and PASSWORD_CHECK_BREACHED (default
+// true). The breached password list is bundled with the application and can
+// be replaced with BREACHED_PASSWORDS_FILE; it is never sent anywhere.
+func ValidatePassword(password, username, email string) error {
+	var violations []PasswordViolation
+	lower := strings.ToLower(password)
+
+	minLength := intFromEnv("PASSWORD_MIN_LENGTH", defaultPasswordMinLength)
+	if utf8.RuneCountInString(password) < minLength {
+		violations = append(violations, PasswordViolation{PasswordTooShort, fmt.Sprintf("Password must be at least %d characters long", minLength)})
+	}
+	if len(password) > passwordMaxBytes {
+		violations = append(violations, PasswordViolation{PasswordTooLong, fmt.Sprintf("Password must be at most %d bytes long", passwordMaxBytes)})
+	}
+	common := boolFromEnv("PASSWORD_BLOCK_COMMON", true) && isCommonPassword(lower)
+	if common {
+		violations = append(violations, PasswordViolation{PasswordCommon, "Password is too common"})
+	}
+	if username = strings.ToLower(strings.TrimSpace(username)); len(username) >= 3 && strings.Contains(lower, username) {
+		violations = append(violations, PasswordViolation{PasswordContainsUsername, "Password must not contain the username"})
+	}
+	if local, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@"); len(local) >= 3 && strings.Contains(lower, local) {
+		violations = append(violations, PasswordViolation{PasswordContainsEmail, "Password must not contain the email address"})
+	}
+	// Common passwords appear in every breach, so they are only reported once
+	if !common && boolFromEnv("PASSWORD_CHECK_BREACHED", true) && password != "" && isBreachedPassword(password) {
+		violations = append(violations, PasswordViolation{PasswordBreached, "Password has appeared in a data breach"})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func isCommonPassword(lower string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = map[string]bool{}
+		forEachLine(strings.NewReader(bundledCommonPasswords), func(line string) {
+			commonPasswords[strings.ToLower(line)] = true
+		})
+	})
+	return commonPasswords[lower]
+}
+
+// isBreachedPassword looks the SHA-1 hash of the password up in the breached
+// password list, which is bucketed by hash prefix.
+func isBreachedPassword(password string) bool {
+	breachedPasswordsOnce.Do(loadBreachedPasswords)
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return breachedPasswords[hash[:breachedPrefixLength]][hash[breachedPrefixLength:]]
+}
+
+func loadBreachedPasswords() {
+	breachedPasswords = map[string]map[string]bool{}
+
+	var source io.Reader = strings.NewReader(bundledBreachedPasswords)
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Printf("Failed to open BREACHED_PASSWORDS_FILE, using the bundled list: %v", err)
+		} else {
+			defer file.Close()
+			source = file
+		}
+	}
+
+	forEachLine(source, func(line string) {
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != 2*sha1.Size {
+			return
+		}
+		prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+		if breachedPasswords[prefix] == nil {
+			breachedPasswords[prefix] = map[string]bool{}
+		}
+		breachedPasswords[prefix][suffix] = true
+	})
+}
+
+// forEachLine calls fn with every non-empty line that is not a # comment.
+func forEachLine(r io.Reader, fn func(line string)) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fn(line)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Failed to read password list: %v", err)
+	}
+}
+
+func boolFromEnv(key string, fallback bool) bool {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "true", "1", "yes":
+		return true
+	case "false", "0", "no":
+		return false
+	default:
+		return fallback
+	}
+}
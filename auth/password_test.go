@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "10")
+	t.Setenv("PASSWORD_BLOCK_COMMON", "true")
+	t.Setenv("PASSWORD_CHECK_BREACHED", "true")
+	t.Setenv("BREACHED_PASSWORDS_FILE", "")
+
+	tests := []struct {
+		name, password, username, email string
+		want                            []string
+	}{
+		{"good", "correct horse battery", "alice", "alice@example.com", nil},
+		{"too short", "k8#qLz", "alice", "alice@example.com", []string{PasswordTooShort}},
+		{"too long", strings.Repeat("x7!", 30), "alice", "alice@example.com", []string{PasswordTooLong}},
+		{"common, reported once", "1234567890", "alice", "alice@example.com", []string{PasswordCommon}},
+		{"common ignores case", "QWERTYUIOP", "alice", "alice@example.com", []string{PasswordCommon}},
+		{"breached", "Password1!", "alice", "alice@example.com", []string{PasswordBreached}},
+		{"contains username", "my-Alice-is-long", "alice", "someone@example.com", []string{PasswordContainsUsername}},
+		{"contains email", "bobsmith-forever", "alice", "bobsmith@example.com", []string{PasswordContainsEmail}},
+		{"short username is ignored", "al-is-a-good-start", "al", "x@example.com", nil},
+		{"several violations", "alice", "alice", "alice@example.com", []string{PasswordTooShort, PasswordContainsUsername, PasswordContainsEmail}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePassword(tt.password, tt.username, tt.email)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("ValidatePassword(%q) = %v, want nil", tt.password, err)
+				}
+				return
+			}
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("ValidatePassword(%q) = %v, want a *PasswordPolicyError", tt.password, err)
+			}
+			var codes []string
+			for _, v := range policyErr.Violations {
+				codes = append(codes, v.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.want) {
+				t.Errorf("ValidatePassword(%q) violations = %v, want %v", tt.password, codes, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePasswordSwitches(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "4")
+	t.Setenv("PASSWORD_BLOCK_COMMON", "false")
+	t.Setenv("PASSWORD_CHECK_BREACHED", "false")
+
+	for _, password := range []string{"1234567890", "Password1!"} {
+		if err := ValidatePassword(password, "alice", "alice@example.com"); err != nil {
+			t.Errorf("ValidatePassword(%q) = %v with the checks turned off", password, err)
+		}
+	}
+}
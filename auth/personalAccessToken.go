@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessTokenPrefix marks bearer tokens that are personal access
+// tokens rather than JWTs.
+const PersonalAccessTokenPrefix = "blog_pat_"
+
+// ErrInvalidPersonalAccessToken is returned when a personal access token is unknown, expired or revoked.
+var ErrInvalidPersonalAccessToken = errors.New("invalid personal access token")
+
+// IsPersonalAccessToken reports whether the bearer token is a personal access token.
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// GeneratePersonalAccessToken returns a new personal access token, its hash
+// and the prefix shown in token listings.
+func GeneratePersonalAccessToken() (string, string, string, error) {
+	random, _, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	token := PersonalAccessTokenPrefix + random
+	return token, HashToken(token), token[:len(PersonalAccessTokenPrefix)+6], nil
+}
+
+// AuthenticatePersonalAccessToken looks up an active personal access token and records its use.
+func AuthenticatePersonalAccessToken(token string) (models.PersonalAccessToken, error) {
+	var pat models.PersonalAccessToken
+	err := initializer.DB.
+		Where("token_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", HashToken(token), time.Now()).
+		First(&pat).Error
+	if err != nil {
+		return pat, ErrInvalidPersonalAccessToken
+	}
+
+	initializer.DB.Model(&pat).UpdateColumn("last_used_at", time.Now())
+	return pat, nil
+}
+
+// RevokeAllPersonalAccessTokens revokes every active personal access token of the user.
+func RevokeAllPersonalAccessTokens(userID uint) error {
+	return revokePersonalAccessTokens(initializer.DB, userID)
+}
+
+func revokePersonalAccessTokens(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
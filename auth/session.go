@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired or revoked.
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// TokenPair is the pair of tokens handed out on login and on refresh.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// sessionTouchInterval limits how often the last-seen time of a session is
+// written, so that a burst of requests does not cause a burst of updates.
+const sessionTouchInterval = time.Minute
+
+// StartSession creates a new session for the user and issues its first token pair.
+// mfaVerified records whether the user passed a second factor when logging in;
+// userAgent and ip describe the device so the user can recognise the session.
+func StartSession(user models.User, mfaVerified bool, userAgent, ip string) (TokenPair, error) {
+	var pair TokenPair
+	err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		session := models.Session{
+			UserID:      user.ID,
+			ExpiresAt:   now.Add(RefreshTokenTTL()),
+			MFAVerified: mfaVerified,
+			UserAgent:   userAgent,
+			IP:          ip,
+			LastSeenAt:  now,
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		pair, err = issueTokenPair(tx, user, session)
+		return err
+	})
+	return pair, err
+}
+
+// RotateRefreshToken exchanges a refresh token for a new token pair. The
+// presented token is revoked. Presenting an already revoked token is treated
+// as token theft and revokes the whole session.
+func RotateRefreshToken(token string) (models.User, TokenPair, error) {
+	var user models.User
+	var pair TokenPair
+	var reusedSessionID uint
+
+	err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		var refreshToken models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&refreshToken, "token_hash = ?", HashToken(token)).Error; err != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		now := time.Now()
+		if refreshToken.RevokedAt != nil {
+			reusedSessionID = refreshToken.SessionID
+			return ErrInvalidRefreshToken
+		}
+		if now.After(refreshToken.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		var session models.Session
+		if err := tx.First(&session, refreshToken.SessionID).Error; err != nil {
+			return ErrInvalidRefreshToken
+		}
+		if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.First(&user, refreshToken.UserID).Error; err != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.Model(&refreshToken).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		var err error
+		pair, err = issueTokenPair(tx, user, session)
+		return err
+	})
+	if reusedSessionID != 0 {
+		RevokeSession(reusedSessionID)
+	}
+	if err != nil {
+		return models.User{}, TokenPair{}, err
+	}
+	return user, pair, nil
+}
+
+// RevokeSession revokes a single session and all of its refresh tokens.
+func RevokeSession(sessionID uint) error {
+	return revokeSessions(initializer.DB, "id = ?", sessionID)
+}
+
+// RevokeAllSessions revokes every session of the user.
+func RevokeAllSessions(userID uint) error {
+	return revokeSessions(initializer.DB, "user_id = ?", userID)
+}
+
+// RevokeOtherSessions revokes every session of the user except the current one.
+func RevokeOtherSessions(userID, currentSessionID uint) error {
+	return revokeSessions(initializer.DB, "user_id = ? AND id <> ?", userID, currentSessionID)
+}
+
+// ActiveSession returns the session if it exists, is not expired and has not been revoked.
+func ActiveSession(sessionID uint) (models.Session, bool) {
+	var session models.Session
+	err := initializer.DB.
+		Where("revoked_at IS NULL AND expires_at > ?", time.Now()).
+		First(&session, sessionID).Error
+	return session, err == nil
+}
+
+// TouchSession records that the session was just used from the IP address.
+func TouchSession(session models.Session, ip string) {
+	if time.Since(session.LastSeenAt) < sessionTouchInterval && session.IP == ip {
+		return
+	}
+	initializer.DB.Model(&session).UpdateColumns(map[string]interface{}{
+		"last_seen_at": time.Now(),
+		"ip":           ip,
+	})
+}
+
+func revokeSessions(db *gorm.DB, query string, args ...interface{}) error {
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		var sessionIDs []uint
+		if err := tx.Model(&models.Session{}).Where(query, args...).Pluck("id", &sessionIDs).Error; err != nil {
+			return err
+		}
+		if len(sessionIDs) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.Session{}).
+			Where("id IN ? AND revoked_at IS NULL", sessionIDs).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("session_id IN ? AND revoked_at IS NULL", sessionIDs).
+			Update("revoked_at", now).Error
+	})
+}
+
+func issueTokenPair(tx *gorm.DB, user models.User, session models.Session) (TokenPair, error) {
+	accessToken, err := GenerateAccessToken(user, session.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	refreshToken, hash, err := GenerateOpaqueToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	record := models.RefreshToken{
+		SessionID: session.ID,
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(AccessTokenTTL().Seconds()),
+	}, nil
+}
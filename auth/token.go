@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+)
+
+const (
+	defaultAccessTokenTTL   = 15 * time.Minute
+	defaultRefreshTokenTTL  = 30 * 24 * time.Hour
+	defaultPasswordResetTTL = time.Hour
+	defaultEmailVerifyTTL   = 24 * time.Hour
+	defaultImpersonationTTL = 15 * time.Minute
+
+	// MFAPendingTTL is how long a user has to enter the second factor after the password.
+	MFAPendingTTL = 5 * time.Minute
+)
+
+// AccessTokenTTL returns the lifetime of access tokens, read from the
+// ACCESS_TOKEN_TTL environment variable (e.g. "15m").
+func AccessTokenTTL() time.Duration {
+	return durationFromEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+// RefreshTokenTTL returns the lifetime of refresh tokens, read from the
+// REFRESH_TOKEN_TTL environment variable (e.g. "720h").
+func RefreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
+
+// PasswordResetTTL returns how long a password reset link stays valid, read
+// from the PASSWORD_RESET_TTL environment variable.
+func PasswordResetTTL() time.Duration {
+	return durationFromEnv("PASSWORD_RESET_TTL", defaultPasswordResetTTL)
+}
+
+// EmailVerificationTTL returns how long an email verification link stays
+// valid, read from the EMAIL_VERIFICATION_TTL environment variable.
+func EmailVerificationTTL() time.Duration {
+	return durationFromEnv("EMAIL_VERIFICATION_TTL", defaultEmailVerifyTTL)
+}
+
+// ImpersonationTTL returns the lifetime of impersonation tokens, read from
+// the IMPERSONATION_TTL environment variable.
+func ImpersonationTTL() time.Duration {
+	return durationFromEnv("IMPERSONATION_TTL", defaultImpersonationTTL)
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
+// GenerateAccessToken signs a short-lived access token for the user bound to the given session.
+func GenerateAccessToken(user models.User, sessionID uint) (string, error) {
+	return signToken(jwt.MapClaims{
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+		"sid": sessionID,
+		"typ": "access",
+		"exp": time.Now().Add(AccessTokenTTL()).Unix(),
+	})
+}
+
+// GenerateImpersonationToken signs an access token that acts as the user on
+// behalf of the admin. The act claim (RFC 8693) carries the admin's ID and
+// the token is bound to the admin's session, so logging the admin out also
+// ends the impersonation. There is no refresh token.
+func GenerateImpersonationToken(admin models.User, user models.User, sessionID uint) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ImpersonationTTL())
+	token, err := signToken(jwt.MapClaims{
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+		"act": map[string]interface{}{"sub": strconv.FormatUint(uint64(admin.ID), 10)},
+		"sid": sessionID,
+		"typ": "access",
+		"exp": expiresAt.Unix(),
+	})
+	return token, expiresAt, err
+}
+
+// ActorID returns the ID of the admin acting on behalf of the subject of an
+// impersonation token. ok is false for ordinary access tokens.
+func ActorID(claims jwt.MapClaims) (uint, bool, error) {
+	act, found := claims["act"]
+	if !found {
+		return 0, false, nil
+	}
+	actor, isMap := act.(map[string]interface{})
+	if !isMap {
+		return 0, true, errors.New("malformed act claim")
+	}
+	id, err := SubjectID(jwt.MapClaims(actor))
+	return id, true, err
+}
+
+// ParseAccessToken verifies the signature and expiry of an access token and returns its claims.
+func ParseAccessToken(tokenString string) (jwt.MapClaims, error) {
+	return parseToken(tokenString, "access")
+}
+
+// GenerateEmailVerificationToken signs a token proving that the user controls the email address.
+func GenerateEmailVerificationToken(user models.User, email string) (string, error) {
+	return signToken(jwt.MapClaims{
+		"sub":   strconv.FormatUint(uint64(user.ID), 10),
+		"email": email,
+		"typ":   "email_verification",
+		"exp":   time.Now().Add(EmailVerificationTTL()).Unix(),
+	})
+}
+
+// ParseEmailVerificationToken verifies an email verification token and returns
+// the ID of the user and the email address it was issued for.
+func ParseEmailVerificationToken(tokenString string) (uint, string, error) {
+	claims, err := parseToken(tokenString, "email_verification")
+	if err != nil {
+		return 0, "", err
+	}
+	userID, err := SubjectID(claims)
+	if err != nil {
+		return 0, "", err
+	}
+	email, ok := claims["email"].(string)
+	if !ok || email == "" {
+		return 0, "", errors.New("missing email claim")
+	}
+	return userID, email, nil
+}
+
+// GenerateMFAPendingToken signs a short-lived token proving that the user
+// passed the password step of a login that still needs a second factor.
+func GenerateMFAPendingToken(user models.User) (string, error) {
+	return signToken(jwt.MapClaims{
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+		"typ": "mfa_pending",
+		"exp": time.Now().Add(MFAPendingTTL).Unix(),
+	})
+}
+
+// ParseMFAPendingToken verifies an mfa pending token and returns the ID of the user.
+func ParseMFAPendingToken(tokenString string) (uint, error) {
+	claims, err := parseToken(tokenString, "mfa_pending")
+	if err != nil {
+		return 0, err
+	}
+	return SubjectID(claims)
+}
+
+// SubjectID returns the user ID stored in the sub claim.
+func SubjectID(claims jwt.MapClaims) (uint, error) {
+	sub, err := claims.GetSubject()
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(sub, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// signToken signs the claims with the active RS256 key and records its kid in the header.
+func signToken(claims jwt.MapClaims) (string, error) {
+	key := initializer.ActiveSigningKey
+	if key == nil {
+		return "", errors.New("no signing key loaded")
+	}
+	if issuer := Issuer(); issuer != "" {
+		claims["iss"] = issuer
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// parseToken verifies a token against the verification key named by its kid
+// and checks that it has the expected type.
+func parseToken(tokenString string, typ string) (jwt.MapClaims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if issuer := Issuer(); issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := initializer.VerificationKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		return key.PublicKey, nil
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != typ {
+		return nil, errors.New("invalid token type")
+	}
+	return claims, nil
+}
+
+// Issuer returns the value of the iss claim, read from JWT_ISSUER.
+func Issuer() string {
+	return os.Getenv("JWT_ISSUER")
+}
+
+// GenerateOpaqueToken returns a random URL-safe token together with the hash
+// that should be stored in the database in its place.
+func GenerateOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hex encoded SHA-256 hash of an opaque token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the base32 encoding of the RFC 6238 test key "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits
+	at := time.Unix(1111111109, 0)
+	step := at.Unix() / totpPeriod
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", "081804", at, 0, step, true},
+		{"spaces are ignored", "081 804", at, 0, step, true},
+		{"previous step within skew", "081804", at.Add(totpPeriod * time.Second), 0, step, true},
+		{"next step within skew", "081804", at.Add(-totpPeriod * time.Second), 0, step, true},
+		{"outside skew", "081804", at.Add(2 * totpPeriod * time.Second), 0, 0, false},
+		{"replayed step", "081804", at, step, 0, false},
+		{"earlier step used", "081804", at, step - 1, step, true},
+		{"wrong code", "123456", at, 0, 0, false},
+		{"wrong length", "81804", at, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := ValidateTOTP(rfcSecret, tt.code, tt.at, tt.lastStep)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP(%q) = %d, %v, want %d, %v", tt.code, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTOTPCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPLowercaseSecret(t *testing.T) {
+	if _, ok := ValidateTOTP(strings.ToLower(rfcSecret), "081804", time.Unix(1111111109, 0), 0); !ok {
+		t.Error("a lowercase secret was rejected")
+	}
+}
+
+func TestValidateTOTPInvalidSecret(t *testing.T) {
+	if _, ok := ValidateTOTP("not base32!", "000000", time.Now(), 0); ok {
+		t.Error("an invalid secret accepted a code")
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("recovery code %q is not formatted as xxxxx-xxxxx", code)
+		}
+	}
+	if got := NormalizeRecoveryCode(" ABCDE-fghij "); got != "abcdefghij" {
+		t.Errorf("NormalizeRecoveryCode = %q, want abcdefghij", got)
+	}
+}
@@ -0,0 +1,17 @@
+package auth
+
+import "strings"
+
+// reservedUsernames are the static paths under /users that would shadow the
+// public profile, follow, block and mute routes of a user with that name.
+var reservedUsernames = map[string]bool{
+	"2fa": true, "blocks": true, "deletion": true, "export": true, "login": true,
+	"logout": true, "mutes": true, "password": true, "profile": true, "sessions": true,
+	"signup": true, "token": true, "tokens": true, "verify-email": true,
+}
+
+// IsReservedUsername reports whether the username cannot be used because it
+// clashes with an endpoint.
+func IsReservedUsername(username string) bool {
+	return reservedUsernames[strings.ToLower(strings.TrimSpace(username))]
+}
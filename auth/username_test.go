@@ -0,0 +1,18 @@
+package auth
+
+import "testing"
+
+func TestIsReservedUsername(t *testing.T) {
+	tests := map[string]bool{
+		"profile":  true,
+		"Sessions": true,
+		" 2fa ":    true,
+		"alice":    false,
+		"profiles": false,
+	}
+	for username, want := range tests {
+		if got := IsReservedUsername(username); got != want {
+			t.Errorf("IsReservedUsername(%q) = %v, want %v", username, got, want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/auth"
+	"github.com/khunaungpaing/the-blog-api/dto"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ExportUserData returns everything stored about the logged-in user.
+// @Summary Export personal data
+// @Description Download the profile, posts (with categories, tags and media metadata), post revisions, comments and likes of the logged-in user as a ZIP archive of JSON files, or as a single JSON document with format=json.
+// @Tags users
+// @Produce application/zip
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param format query string false "zip (default) or json"
+// @Success 200 {object} dto.DataExport "Personal data"
+// @Failure 400 {object} gin.H "Unknown format"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/export [get]
+func ExportUserData(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "zip")
+	if format != "zip" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown format, use zip or json"})
+		return
+	}
+
+	export := dto.DataExport{ExportedAt: time.Now(), Profile: userModel}
+	export.Profile.Password = ""
+	if err := initializer.DB.Preload("Categories").Preload("Tags").Preload("Media").
+		Where("user_id = ?", userModel.ID).Order("id").Find(&export.Posts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+	if err := initializer.DB.Where("user_id = ?", userModel.ID).Order("id").Find(&export.Comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+	if err := initializer.DB.Where("user_id = ?", userModel.ID).Order("id").Find(&export.Likes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch likes"})
+		return
+	}
+
+	userPosts := initializer.DB.Model(&models.Post{}).Select("id").Where("user_id = ?", userModel.ID)
+	if err := initializer.DB.Where("post_id IN (?) OR author_id = ?", userPosts, userModel.ID).Order("post_id, number").Find(&export.Revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-export-%s", userModel.Username, export.ExportedAt.Format("20060102"))
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"posts.json", export.Posts},
+		{"comments.json", export.Comments},
+		{"likes.json", export.Likes},
+		{"revisions.json", export.Revisions},
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		c.Error(err)
+	}
+}
+
+// DeleteAccount schedules the deletion of the logged-in user's account.
+// @Summary Delete account
+// @Description Schedules the account for deletion after a grace period (ACCOUNT_DELETION_GRACE_PERIOD, default 30 days) and signs the user out everywhere. With mode anonymize the posts and comments are kept without anything that identifies the user; with mode cascade they are deleted too. Requires the current password.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param body body models.DeleteAccountRequest true "Password and deletion mode"
+// @Success 202 {object} gin.H "Deletion scheduled"
+// @Failure 400 {object} gin.H "Invalid request body or unknown mode"
+// @Failure 401 {object} gin.H "Unauthorized access or wrong password"
+// @Failure 409 {object} gin.H "Deletion already scheduled"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users [delete]
+func DeleteAccount(c *gin.Context) {
+	var body models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if body.Mode == "" {
+		body.Mode = models.DeletionModeAnonymize
+	}
+	if body.Mode != models.DeletionModeAnonymize && body.Mode != models.DeletionModeCascade {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown mode, use anonymize or cascade"})
+		return
+	}
+
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	if userModel.DeletionScheduledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Account deletion is already scheduled", "deletion_scheduled_at": userModel.DeletionScheduledAt})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(userModel.Password), []byte(body.Password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+
+	scheduledAt, err := auth.ScheduleAccountDeletion(userModel, body.Mode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule account deletion"})
+		return
+	}
+
+	auth.RecordAuthEvent(models.AuthEvent{
+		UserID:    &userModel.ID,
+		Email:     userModel.Email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Event:     models.AuthEventDeletionRequested,
+		Reason:    body.Mode,
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":               "Account deletion scheduled. Log in and cancel it before then to keep your account.",
+		"deletion_scheduled_at": scheduledAt,
+		"deletion_mode":         body.Mode,
+	})
+}
+
+// CancelAccountDeletion keeps the logged-in user's account.
+// @Summary Cancel account deletion
+// @Description Cancels a scheduled account deletion during the grace period.
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {object} gin.H "Deletion cancelled"
+// @Failure 400 {object} gin.H "No deletion scheduled"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/deletion/cancel [post]
+func CancelAccountDeletion(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	if userModel.DeletionScheduledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account deletion is not scheduled"})
+		return
+	}
+	if err := auth.CancelAccountDeletion(userModel.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel account deletion"})
+		return
+	}
+
+	auth.RecordAuthEvent(models.AuthEvent{
+		UserID:    &userModel.ID,
+		Email:     userModel.Email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Event:     models.AuthEventDeletionCancelled,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+}
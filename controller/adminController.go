@@ -0,0 +1,340 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/auth"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
+	"gorm.io/gorm/clause"
+)
+
+// ListUsers retrieves a paginated list of users with their roles.
+// @Summary List users
+// @Description Retrieve a paginated list of users with their roles. Requires the users:manage permission.
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Number of items per page (default: 10)"
+// @Success 200 {object} gin.H "List of users"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Permission denied"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /admin/users [get]
+func ListUsers(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	var totalUsersCount int64
+	if err := initializer.DB.Model(&models.User{}).Count(&totalUsersCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch total users count"})
+		return
+	}
+
+	var users []models.User
+	if err := initializer.DB.Preload("Role").Order("id").Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	for i := range users {
+		users[i].Password = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":       users,
+		"currentPage": page,
+		"pageSize":    pageSize,
+		"totalCount":  totalUsersCount,
+	})
+}
+
+// ListRoles retrieves all roles with their permissions.
+// @Summary List roles
+// @Description Retrieve all roles with their permissions. Requires the users:manage permission.
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {array} models.Role "List of roles"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Permission denied"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /admin/roles [get]
+func ListRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := initializer.DB.Preload("Permissions").Order("id").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// UpdateUserRole assigns a role to a user.
+// @Summary Change a user's role
+// @Description Assign a role to the specified user. Requires the users:manage permission.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param userId path int true "User ID"
+// @Param body body models.UpdateRoleRequest true "Role name"
+// @Success 200 {object} models.User "Updated user"
+// @Failure 400 {object} gin.H "Bad request, invalid request body or unknown role"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Permission denied"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /admin/users/{userId}/role [patch]
+func UpdateUserRole(c *gin.Context) {
+	var body models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var user models.User
+	if err := initializer.DB.Where("id = ?", c.Param("userId")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var role models.Role
+	if err := initializer.DB.First(&role, "name = ?", body.Role).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+
+	user.RoleID = role.ID
+	if err := initializer.DB.Omit(clause.Associations).Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	user.Role = &role
+	user.Password = ""
+	c.JSON(http.StatusOK, user)
+}
+
+// UnlockUser lifts the login lockout of a user.
+// @Summary Unlock a user account
+// @Description Clears the failed login attempts and lockout of the specified user. Requires the users:manage permission.
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param userId path int true "User ID"
+// @Success 200 {object} gin.H "Account unlocked"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Permission denied"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /admin/users/{userId}/unlock [post]
+func UnlockUser(c *gin.Context) {
+	var user models.User
+	if err := initializer.DB.Where("id = ?", c.Param("userId")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := auth.UnlockAccount(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+		return
+	}
+
+	admin, _ := c.Get("user")
+	adminModel, _ := admin.(models.User)
+	auth.RecordAuthEvent(models.AuthEvent{
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Event:     models.AuthEventAccountUnlocked,
+		Reason:    "unlocked by " + adminModel.Username,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
+
+// ListAuthEvents retrieves the auth event log, newest first.
+// @Summary List auth events
+// @Description Retrieve the authentication event log, optionally filtered by user, email, IP or event type. Requires the users:manage permission.
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param userId query int false "Filter by user ID"
+// @Param email query string false "Filter by email"
+// @Param ip query string false "Filter by IP address"
+// @Param event query string false "Filter by event type"
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Number of items per page (default: 50)"
+// @Success 200 {object} gin.H "List of auth events"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Permission denied"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /admin/auth-events [get]
+func ListAuthEvents(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+	if err != nil || pageSize < 1 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	dbQuery := initializer.DB.Model(&models.AuthEvent{})
+	if userID := c.Query("userId"); userID != "" {
+		dbQuery = dbQuery.Where("user_id = ?", userID)
+	}
+	if email := c.Query("email"); email != "" {
+		dbQuery = dbQuery.Where("email = ?", email)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		dbQuery = dbQuery.Where("ip = ?", ip)
+	}
+	if event := c.Query("event"); event != "" {
+		dbQuery = dbQuery.Where("event = ?", event)
+	}
+
+	var totalCount int64
+	if err := dbQuery.Count(&totalCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch total events count"})
+		return
+	}
+
+	var events []models.AuthEvent
+	if err := dbQuery.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch auth events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      events,
+		"currentPage": page,
+		"pageSize":    pageSize,
+		"totalCount":  totalCount,
+	})
+}
+
+// ImpersonateUser issues a short-lived token that acts as the user.
+// @Summary Impersonate a user
+// @Description Issues an access token that acts as the specified user on behalf of the admin, valid for IMPERSONATION_TTL (default 15m). The token carries both identities, cannot reach account management endpoints and every write made with it is recorded in the audit log. Users who can manage users themselves cannot be impersonated. Requires the users:manage permission.
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param userId path int true "User ID"
+// @Success 200 {object} gin.H "Impersonation token"
+// @Failure 400 {object} gin.H "Cannot impersonate this user"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Permission denied"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /admin/users/{userId}/impersonate [post]
+func ImpersonateUser(c *gin.Context) {
+	admin, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	adminModel, ok := admin.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	var user models.User
+	if err := initializer.DB.Preload("Role.Permissions").Where("id = ?", c.Param("userId")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.ID == adminModel.ID || policy.Can(user, policy.ManageUsers) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot impersonate this user"})
+		return
+	}
+
+	token, expiresAt, err := auth.GenerateImpersonationToken(adminModel, user, c.GetUint("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create impersonation token"})
+		return
+	}
+
+	auth.RecordAuthEvent(models.AuthEvent{
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Event:     models.AuthEventImpersonation,
+		Reason:    "impersonated by " + adminModel.Username,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_at": expiresAt,
+		"user_id":    user.ID,
+		"username":   user.Username,
+	})
+}
+
+// ListAuditLog retrieves the writes made while impersonating, newest first.
+// @Summary List the impersonation audit log
+// @Description Retrieve the writes admins made while impersonating users, optionally filtered by admin or impersonated user. Requires the users:manage permission.
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param impersonatorId query int false "Filter by admin ID"
+// @Param userId query int false "Filter by impersonated user ID"
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Number of items per page (default: 50)"
+// @Success 200 {object} gin.H "List of audit log entries"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Permission denied"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /admin/audit-log [get]
+func ListAuditLog(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "50"))
+	if err != nil || pageSize < 1 {
+		pageSize = 50
+	}
+	offset := (page - 1) * pageSize
+
+	dbQuery := initializer.DB.Model(&models.AuditLog{})
+	if impersonatorID := c.Query("impersonatorId"); impersonatorID != "" {
+		dbQuery = dbQuery.Where("impersonator_id = ?", impersonatorID)
+	}
+	if userID := c.Query("userId"); userID != "" {
+		dbQuery = dbQuery.Where("user_id = ?", userID)
+	}
+
+	var totalCount int64
+	if err := dbQuery.Count(&totalCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch total entries count"})
+		return
+	}
+
+	var entries []models.AuditLog
+	if err := dbQuery.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":     entries,
+		"currentPage": page,
+		"pageSize":    pageSize,
+		"totalCount":  totalCount,
+	})
+}
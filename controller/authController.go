@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/auth"
+	"github.com/khunaungpaing/the-blog-api/models"
+)
+
+// RefreshToken exchanges a refresh token for a new access and refresh token.
+// @Summary Refresh the access token
+// @Description Exchanges a refresh token for a new token pair. The presented refresh token is revoked.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} auth.TokenPair
+// @Failure 400 {object} gin.H "Invalid request"
+// @Failure 401 {object} gin.H "Invalid or revoked refresh token"
+// @Router /users/token/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var body models.RefreshRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		return
+	}
+
+	_, tokens, err := auth.RotateRefreshToken(body.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the session of the current access token.
+// @Summary Logout
+// @Description Revokes the current session together with its refresh tokens.
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {object} gin.H "Logged out successfully"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/logout [post]
+func Logout(c *gin.Context) {
+	sessionID := c.GetUint("session_id")
+	if err := auth.RevokeSession(sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every session and personal access token of the logged-in user.
+// @Summary Logout everywhere
+// @Description Revokes all sessions, refresh tokens and personal access tokens of the logged-in user.
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {object} gin.H "Logged out from all sessions"
+// @Failure 400 {object} gin.H "User not found in context"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/logout/all [post]
+func LogoutAll(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	if err := auth.RevokeAllSessions(userModel.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+	if err := auth.RevokeAllPersonalAccessTokens(userModel.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all sessions"})
+}
@@ -0,0 +1,218 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/dto"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm"
+)
+
+// BlockUser blocks a user for the logged-in user. Follows between the two
+// users in either direction are removed.
+// @Summary Block a user
+// @Description Blocks the user with the given username. Blocked users cannot comment on or like your posts, and their posts and comments are hidden from you.
+// @Tags blocks
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param username path string true "Username"
+// @Success 201 {object} gin.H "Blocked successfully"
+// @Failure 400 {object} gin.H "Bad request, cannot block yourself"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 409 {object} gin.H "Already blocked"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/{username}/block [post]
+func BlockUser(c *gin.Context) {
+	userModel, target, ok := findRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if isBlocked(userModel.ID, target.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already blocked"})
+		return
+	}
+
+	err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.Block{BlockerID: userModel.ID, BlockedID: target.ID}).Error; err != nil {
+			return err
+		}
+		return tx.Where("(follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)",
+			userModel.ID, target.ID, target.ID, userModel.ID).Delete(&models.Follow{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Blocked successfully"})
+}
+
+// UnblockUser removes a block.
+// @Summary Unblock a user
+// @Description Unblocks the user with the given username.
+// @Tags blocks
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param username path string true "Username"
+// @Success 200 {object} gin.H "Unblocked successfully"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/{username}/block [delete]
+func UnblockUser(c *gin.Context) {
+	userModel, target, ok := findRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := initializer.DB.Where("blocker_id = ? AND blocked_id = ?", userModel.ID, target.ID).Delete(&models.Block{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unblocked successfully"})
+}
+
+// MuteUser mutes a user for the logged-in user.
+// @Summary Mute a user
+// @Description Mutes the user with the given username. Their posts and comments are hidden from post lists, comments and the feed.
+// @Tags blocks
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param username path string true "Username"
+// @Success 201 {object} gin.H "Muted successfully"
+// @Failure 400 {object} gin.H "Bad request, cannot mute yourself"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 409 {object} gin.H "Already muted"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/{username}/mute [post]
+func MuteUser(c *gin.Context) {
+	userModel, target, ok := findRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	var existingMute models.Mute
+	if err := initializer.DB.Where("muter_id = ? AND muted_id = ?", userModel.ID, target.ID).First(&existingMute).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already muted"})
+		return
+	}
+
+	if err := initializer.DB.Create(&models.Mute{MuterID: userModel.ID, MutedID: target.ID}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mute user"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Muted successfully"})
+}
+
+// UnmuteUser removes a mute.
+// @Summary Unmute a user
+// @Description Unmutes the user with the given username.
+// @Tags blocks
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param username path string true "Username"
+// @Success 200 {object} gin.H "Unmuted successfully"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/{username}/mute [delete]
+func UnmuteUser(c *gin.Context) {
+	userModel, target, ok := findRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := initializer.DB.Where("muter_id = ? AND muted_id = ?", userModel.ID, target.ID).Delete(&models.Mute{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmute user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unmuted successfully"})
+}
+
+// ListBlockedUsers lists the users the logged-in user has blocked.
+// @Summary List blocked users
+// @Description Retrieve the users blocked by the logged-in user.
+// @Tags blocks
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {array} dto.PublicUser "Blocked users"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/blocks [get]
+func ListBlockedUsers(c *gin.Context) {
+	listRelatedUsers(c, "blocks", "blocker_id", "blocked_id")
+}
+
+// ListMutedUsers lists the users the logged-in user has muted.
+// @Summary List muted users
+// @Description Retrieve the users muted by the logged-in user.
+// @Tags blocks
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {array} dto.PublicUser "Muted users"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/mutes [get]
+func ListMutedUsers(c *gin.Context) {
+	listRelatedUsers(c, "mutes", "muter_id", "muted_id")
+}
+
+func listRelatedUsers(c *gin.Context, table, ownerColumn, targetColumn string) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	users := []dto.PublicUser{}
+	if err := initializer.DB.Model(&models.User{}).
+		Select("users.id, users.username, users.bio, users.profile_pic").
+		Joins("JOIN "+table+" ON "+table+"."+targetColumn+" = users.id").
+		Where(table+"."+ownerColumn+" = ?", userModel.ID).
+		Order(table + ".created_at DESC").
+		Scan(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// findRelationTarget returns the logged-in user and the user named in the route.
+func findRelationTarget(c *gin.Context) (models.User, models.User, bool) {
+	var target models.User
+
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return models.User{}, target, false
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return models.User{}, target, false
+	}
+
+	if err := initializer.DB.First(&target, "username = ?", c.Param("username")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return userModel, target, false
+	}
+	if target.ID == userModel.ID && c.Request.Method == http.MethodPost {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot do this to yourself"})
+		return userModel, target, false
+	}
+	return userModel, target, true
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/dto"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/taxonomy"
+)
+
+// ListCategories lists every category with the number of published posts filed under it.
+// @Summary List categories
+// @Description Lists every category, sorted by name, with the number of published posts filed under it.
+// @Tags categories
+// @Produce json
+// @Success 200 {array} dto.Term "Categories"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /categories [get]
+func ListCategories(c *gin.Context) {
+	listTerms(c, taxonomy.Categories)
+}
+
+// GetCategory retrieves a single category.
+// @Summary Get a category
+// @Description Retrieves a category with the number of published posts filed under it.
+// @Tags categories
+// @Produce json
+// @Param categoryId path int true "Category ID"
+// @Success 200 {object} dto.Term "Category"
+// @Failure 404 {object} gin.H "Category not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /categories/{categoryId} [get]
+func GetCategory(c *gin.Context) {
+	respondTerm(c, http.StatusOK, taxonomy.Categories, c.Param("categoryId"))
+}
+
+// CreateCategory creates a new category.
+// Names are unique regardless of case and spacing.
+// @Summary Create a category
+// @Description Creates a new category. Names are unique regardless of case and extra whitespace.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param category body dto.RequestTerm true "Category data"
+// @Success 201 {object} dto.Term "Successfully created category"
+// @Failure 400 {object} gin.H "Bad request, invalid request body or blank name"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user may not manage categories"
+// @Failure 409 {object} gin.H "A category with this name already exists, with its ID"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /categories [post]
+func CreateCategory(c *gin.Context) {
+	var request dto.RequestTerm
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	name := taxonomy.CleanName(request.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	category := models.Category{Name: name, NormalizedName: taxonomy.Normalize(name)}
+	if request.Description != nil {
+		category.Description = *request.Description
+	}
+	if err := initializer.DB.Clauses(taxonomy.OnNameConflict).Create(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+	if category.ID == 0 {
+		existingID, _ := nameTaken(taxonomy.Categories, category.NormalizedName, 0)
+		c.JSON(http.StatusConflict, gin.H{"error": "A category with this name already exists", "id": existingID})
+		return
+	}
+
+	respondTerm(c, http.StatusCreated, taxonomy.Categories, category.ID)
+}
+
+// UpdateCategory renames a category or changes its description.
+// @Summary Update a category
+// @Description Renames a category or changes its description. Posts filed under the category keep it. Renaming it to the name of another category is refused; merge them instead.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param categoryId path int true "Category ID"
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param category body dto.RequestTerm true "New name and/or description"
+// @Success 200 {object} dto.Term "Successfully updated category"
+// @Failure 400 {object} gin.H "Bad request, invalid request body or blank name"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user may not manage categories"
+// @Failure 404 {object} gin.H "Category not found"
+// @Failure 409 {object} gin.H "Another category has this name, with its ID"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /categories/{categoryId} [patch]
+func UpdateCategory(c *gin.Context) {
+	updateTerm(c, taxonomy.Categories, "categoryId")
+}
+
+// DeleteCategory deletes a category and removes it from its posts.
+// @Summary Delete a category
+// @Description Deletes a category and removes it from every post filed under it. The posts themselves are kept.
+// @Tags categories
+// @Produce json
+// @Param categoryId path int true "Category ID"
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {object} gin.H "Successfully deleted category"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user may not manage categories"
+// @Failure 404 {object} gin.H "Category not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /categories/{categoryId} [delete]
+func DeleteCategory(c *gin.Context) {
+	deleteTerm(c, taxonomy.Categories, "categoryId")
+}
+
+// MergeCategories merges other categories into a category.
+// @Summary Merge categories
+// @Description Moves the posts of the source categories to this category and deletes the sources.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param categoryId path int true "ID of the category to merge into"
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param merge body dto.RequestMerge true "IDs of the categories to merge"
+// @Success 200 {object} dto.Term "The merged category"
+// @Failure 400 {object} gin.H "Bad request, no sources or the category itself among them"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user may not manage categories"
+// @Failure 404 {object} gin.H "Category not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /categories/{categoryId}/merge [post]
+func MergeCategories(c *gin.Context) {
+	mergeTerms(c, taxonomy.Categories, "categoryId")
+}
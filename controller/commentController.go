@@ -8,6 +8,7 @@ import (
 	"github.com/khunaungpaing/the-blog-api/dto"
 	"github.com/khunaungpaing/the-blog-api/initializer"
 	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
 )
 
 // CreateComment creates a new comment for a specific post.
@@ -22,6 +23,7 @@ import (
 // @Success 201 {object} models.Comment "Successfully created comment"
 // @Failure 400 {object} gin.H "Bad request, invalid postId or request body"
 // @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, the post owner has blocked the user"
 // @Failure 404 {object} gin.H "Post not found"
 // @Failure 500 {object} gin.H "Internal server error"
 // @Router /posts/{postId}/comments [post]
@@ -58,20 +60,27 @@ func CreateComment(c *gin.Context) {
 	newComment.UserID = userModel.ID
 
 	var post models.Post
-	if err := initializer.DB.First(&post, newComment.PostID).Error; err != nil {
+	if err := initializer.DB.First(&post, newComment.PostID).Error; err != nil || !policy.CanViewPost(userModel, post) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post_id"})
 		return
 	}
 
+	if isBlocked(post.UserID, userModel.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot comment on this post"})
+		return
+	}
+
 	if err := initializer.DB.Create(&newComment).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
 		return
 	}
 
+	c.Header("ETag", etag(newComment.Version))
 	c.JSON(http.StatusCreated, newComment)
 }
 
 // GetCommentsForPost retrieves comments for a specific post.
+// Comments by users the caller has muted or blocked are left out.
 // @Summary Get comments for a post
 // @Description Retrieves comments for the specified post.
 // @Tags comments
@@ -80,12 +89,20 @@ func CreateComment(c *gin.Context) {
 // @Param postId path int true "ID of the post"
 // @Param Authorization header string true "Authorization token using the Bearer scheme"
 // @Success 200 {array} models.Comment "Successfully retrieved comments"
+// @Failure 404 {object} gin.H "Post not found"
 // @Failure 500 {object} gin.H "Internal server error"
 // @Router /posts/{postId}/comments [get]
 func GetCommentsForPost(c *gin.Context) {
 	postId := c.Param("postId")
+
+	var post models.Post
+	if err := initializer.DB.Scopes(visiblePosts(viewer(c))).Where("posts.id = ?", postId).First(&post).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
 	var comments []models.Comment
-	if err := initializer.DB.Where("post_id = ?", postId).Find(&comments).Error; err != nil {
+	if err := initializer.DB.Scopes(hiddenAuthors(viewerID(c), "user_id")).Where("post_id = ?", postId).Find(&comments).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
 		return
 	}
@@ -93,28 +110,63 @@ func GetCommentsForPost(c *gin.Context) {
 }
 
 // DeleteComment deletes a specific comment.
+// The comment can be deleted by its author, by the owner of the post or by a moderator.
 // @Summary Delete a comment
 // @Description Deletes the specified comment.
 // @Tags comments
 // @Accept json
 // @Produce json
+// @Param postId path int true "ID of the post"
 // @Param commentId path int true "ID of the comment"
 // @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {object} gin.H "Successfully deleted comment"
+// @Failure 403 {object} gin.H "Forbidden, user is not authorized to delete this comment"
+// @Failure 404 {object} gin.H "Post or comment not found"
+// @Failure 412 {object} gin.H "The comment was changed in the meantime, with its current version"
+// @Failure 428 {object} gin.H "If-Match is required"
 // @Failure 500 {object} gin.H "Internal server error"
 // @Router /posts/{postId}/comments/{commentId} [delete]
 func DeleteComment(c *gin.Context) {
-	commentID := c.Param("commentId")
+	post, comment, ok := findPostComment(c)
+	if !ok {
+		return
+	}
+
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
 
-	if err := initializer.DB.Where("id = ?", commentID).Delete(&models.Comment{}).Error; err != nil {
+	if !policy.CanDeleteComment(userModel, comment, post) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User is not authorized to delete this comment"})
+		return
+	}
+	if !checkIfMatch(c, comment.Version) {
+		return
+	}
+
+	result := initializer.DB.Where("version = ?", comment.Version).Delete(&comment)
+	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
 		return
 	}
+	if result.RowsAffected == 0 {
+		respondStale(c, &models.Comment{}, comment.ID, "Comment not found")
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
 }
 
 // UpdateComment updates a specific comment.
+// Only the author of the comment can update it.
 // @Summary Update a comment
 // @Description Updates the specified comment.
 // @Tags comments
@@ -123,19 +175,39 @@ func DeleteComment(c *gin.Context) {
 // @Param postId path int true "ID of the post"
 // @Param commentId path int true "ID of the comment"
 // @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param If-Match header string false "ETag of the version being updated"
 // @Param comment body dto.RequestComment true "Updated comment object"
 // @Success 200 {object} models.Comment "Successfully updated comment"
 // @Failure 400 {object} gin.H "Bad request, invalid request body"
-// @Failure 404 {object} gin.H "Comment not found"
+// @Failure 403 {object} gin.H "Forbidden, user is not authorized to update this comment"
+// @Failure 404 {object} gin.H "Post or comment not found"
+// @Failure 412 {object} gin.H "The comment was changed in the meantime, with its current version"
+// @Failure 428 {object} gin.H "If-Match is required"
 // @Failure 500 {object} gin.H "Internal server error"
 // @Router /posts/{postId}/comments/{commentId} [patch]
 func UpdateComment(c *gin.Context) {
 	var requestCmt dto.RequestComment
-	var updatedComment models.Comment
-	commentID := c.Param("commentId")
+	_, updatedComment, ok := findPostComment(c)
+	if !ok {
+		return
+	}
 
-	if err := initializer.DB.First(&updatedComment, commentID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	if !policy.CanUpdateComment(userModel, updatedComment) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User is not authorized to update this comment"})
+		return
+	}
+	if !checkIfMatch(c, updatedComment.Version) {
 		return
 	}
 
@@ -143,12 +215,49 @@ func UpdateComment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
-	updatedComment.Content = requestCmt.Content
 
-	if err := initializer.DB.Save(&updatedComment).Error; err != nil {
+	// Only write if nobody changed the comment since it was loaded
+	result := initializer.DB.Model(&updatedComment).Where("version = ?", updatedComment.Version).Updates(map[string]interface{}{
+		"content": requestCmt.Content,
+		"version": updatedComment.Version + 1,
+	})
+	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
 		return
 	}
+	if result.RowsAffected == 0 {
+		respondStale(c, &models.Comment{}, updatedComment.ID, "Comment not found")
+		return
+	}
 
+	c.Header("ETag", etag(updatedComment.Version))
 	c.JSON(http.StatusOK, updatedComment)
 }
+
+// findPostComment loads the post and the comment addressed by the route.
+// A comment that does not belong to the post is reported as not found.
+func findPostComment(c *gin.Context) (models.Post, models.Comment, bool) {
+	var post models.Post
+	var comment models.Comment
+
+	postId, err := strconv.ParseUint(c.Param("postId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return post, comment, false
+	}
+	commentId, err := strconv.ParseUint(c.Param("commentId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return post, comment, false
+	}
+
+	if err := initializer.DB.First(&post, postId).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return post, comment, false
+	}
+	if err := initializer.DB.Where("post_id = ?", post.ID).First(&comment, commentId).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return post, comment, false
+	}
+	return post, comment, true
+}
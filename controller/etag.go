@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+)
+
+// etag returns the entity tag of a post or comment version.
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// ifMatchRequired reports whether writes without an If-Match header are
+// rejected, read from the IF_MATCH_REQUIRED environment variable.
+func ifMatchRequired() bool {
+	required, _ := strconv.ParseBool(os.Getenv("IF_MATCH_REQUIRED"))
+	return required
+}
+
+// checkIfMatch compares the If-Match header of a write with the current
+// version of a post or comment. A stale version gets 412 and a missing header
+// gets 428 if IF_MATCH_REQUIRED is set; either way it responds and returns
+// false.
+func checkIfMatch(c *gin.Context, version uint) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		if !ifMatchRequired() {
+			return true
+		}
+		c.Header("ETag", etag(version))
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "The If-Match header is required", "version": version})
+		return false
+	}
+
+	// If-Match uses the strong comparison, so weak validators (W/"1") never match
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag(version) {
+			return true
+		}
+	}
+	preconditionFailed(c, version)
+	return false
+}
+
+// preconditionFailed responds that the client's copy is out of date, with the
+// current version.
+func preconditionFailed(c *gin.Context, version uint) {
+	c.Header("ETag", etag(version))
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "It was changed in the meantime, reload it and try again", "version": version})
+}
+
+// respondStale answers a conditional write that matched no row: 412 with the
+// current version, or 404 if the row is gone.
+func respondStale(c *gin.Context, model interface{}, id uint, notFound string) {
+	var versions []uint
+	if err := initializer.DB.Model(model).Where("id = ?", id).Pluck("version", &versions).Error; err != nil || len(versions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	preconditionFailed(c, versions[0])
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		header     string
+		required   string
+		wantOK     bool
+		wantStatus int
+	}{
+		{"no header", "", "false", true, http.StatusOK},
+		{"no header when required", "", "true", false, http.StatusPreconditionRequired},
+		{"current version", `"3"`, "true", true, http.StatusOK},
+		{"one of several", `"1", "3"`, "false", true, http.StatusOK},
+		{"any version", "*", "true", true, http.StatusOK},
+		{"stale version", `"2"`, "false", false, http.StatusPreconditionFailed},
+		{"weak validator", `W/"3"`, "false", false, http.StatusPreconditionFailed},
+		{"unquoted", "3", "false", false, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("IF_MATCH_REQUIRED", tt.required)
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPatch, "/posts/1", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Match", tt.header)
+			}
+
+			if ok := checkIfMatch(c, 3); ok != tt.wantOK {
+				t.Fatalf("checkIfMatch = %v, want %v", ok, tt.wantOK)
+			}
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if !tt.wantOK && recorder.Header().Get("ETag") != `"3"` {
+				t.Errorf("ETag = %q, want the current version", recorder.Header().Get("ETag"))
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+)
+
+// maxFeedPageSize caps the number of posts returned by one feed request.
+const maxFeedPageSize = 50
+
+// GetFeed returns the published posts of the authors the logged-in user follows, most recently published first.
+// Pagination uses an opaque cursor so that new posts do not shift the pages.
+// @Summary Get the home feed
+// @Description Retrieve published posts from followed authors, most recently published first. Pass nextCursor from the previous response as cursor to get the next page.
+// @Tags feed
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param cursor query string false "Cursor returned by the previous page"
+// @Param pageSize query int false "Number of items per page (default: 10, max: 50)"
+// @Success 200 {object} gin.H "Posts and the cursor of the next page"
+// @Failure 400 {object} gin.H "Invalid cursor"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /feed [get]
+func GetFeed(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > maxFeedPageSize {
+		pageSize = maxFeedPageSize
+	}
+
+	dbQuery := initializer.DB.Preload("Categories").Preload("Tags").Preload("Media").Preload("User", publicUserColumns).
+		Scopes(hiddenAuthors(userModel.ID, "posts.user_id")).
+		Where("status = ?", models.PostStatusPublished).
+		Where("user_id IN (?)", initializer.DB.Model(&models.Follow{}).Select("followee_id").Where("follower_id = ?", userModel.ID))
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		publishedAt, id, err := decodeFeedCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		dbQuery = dbQuery.Where("("+postDate+", posts.id) < (?, ?)", publishedAt, id)
+	}
+
+	// Fetch one extra post to know whether there is a next page
+	var posts []models.Post
+	if err := dbQuery.Order(postDate + " DESC, posts.id DESC").Limit(pageSize + 1).Find(&posts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feed"})
+		return
+	}
+
+	var nextCursor *string
+	if len(posts) > pageSize {
+		posts = posts[:pageSize]
+		last := posts[len(posts)-1]
+		publishedAt := last.CreatedAt
+		if last.PublishedAt != nil {
+			publishedAt = *last.PublishedAt
+		}
+		cursor := encodeFeedCursor(publishedAt, last.ID)
+		nextCursor = &cursor
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":      posts,
+		"pageSize":   pageSize,
+		"nextCursor": nextCursor,
+	})
+}
+
+// encodeFeedCursor encodes the sort key of the last post of a page.
+func encodeFeedCursor(publishedAt time.Time, id uint) string {
+	raw := fmt.Sprintf("%d:%d", publishedAt.UnixMicro(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(cursor string) (time.Time, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	micros, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return time.Time{}, 0, errors.New("malformed cursor")
+	}
+	us, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	postID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return time.UnixMicro(us), uint(postID), nil
+}
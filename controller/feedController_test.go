@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeedCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		publishedAt time.Time
+		id          uint
+	}{
+		{time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC), 42},
+		{time.Unix(0, 0), 1},
+		{time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), 4294967295},
+	}
+	for _, tt := range tests {
+		publishedAt, id, err := decodeFeedCursor(encodeFeedCursor(tt.publishedAt, tt.id))
+		if err != nil {
+			t.Fatalf("decoding the cursor of %v, %d: %v", tt.publishedAt, tt.id, err)
+		}
+		if !publishedAt.Equal(tt.publishedAt) || id != tt.id {
+			t.Errorf("cursor round trip = %v, %d, want %v, %d", publishedAt, id, tt.publishedAt, tt.id)
+		}
+	}
+}
+
+func TestDecodeFeedCursorRejectsGarbage(t *testing.T) {
+	for _, cursor := range []string{"!!!", "MTIz", "YWJjOjE", "MTIzOng", "MTIzOi0x"} {
+		if _, _, err := decodeFeedCursor(cursor); err == nil {
+			t.Errorf("decodeFeedCursor(%q) succeeded", cursor)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/dto"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+)
+
+// FollowUser makes the logged-in user follow an author.
+// @Summary Follow an author
+// @Description Follow the user with the given username.
+// @Tags follows
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param username path string true "Username of the author"
+// @Success 201 {object} gin.H "Followed successfully"
+// @Failure 400 {object} gin.H "Bad request, cannot follow yourself"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, one of the users has blocked the other"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 409 {object} gin.H "Already following"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/{username}/follow [post]
+func FollowUser(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	var author models.User
+	if err := initializer.DB.First(&author, "username = ?", c.Param("username")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if author.ID == userModel.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot follow yourself"})
+		return
+	}
+
+	if isBlocked(author.ID, userModel.ID) || isBlocked(userModel.ID, author.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot follow this user"})
+		return
+	}
+
+	var existingFollow models.Follow
+	if err := initializer.DB.Where("follower_id = ? AND followee_id = ?", userModel.ID, author.ID).First(&existingFollow).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already following this user"})
+		return
+	}
+
+	follow := models.Follow{
+		FollowerID: userModel.ID,
+		FolloweeID: author.ID,
+	}
+	if err := initializer.DB.Create(&follow).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow user"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Followed successfully"})
+}
+
+// UnfollowUser makes the logged-in user stop following an author.
+// @Summary Unfollow an author
+// @Description Stop following the user with the given username.
+// @Tags follows
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param username path string true "Username of the author"
+// @Success 200 {object} gin.H "Unfollowed successfully"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/{username}/follow [delete]
+func UnfollowUser(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	var author models.User
+	if err := initializer.DB.First(&author, "username = ?", c.Param("username")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := initializer.DB.Where("follower_id = ? AND followee_id = ?", userModel.ID, author.ID).Delete(&models.Follow{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unfollowed successfully"})
+}
+
+// GetFollowers lists the users following an author.
+// @Summary Get followers
+// @Description Retrieve the users following the user with the given username, newest first, with pagination support.
+// @Tags follows
+// @Produce json
+// @Param username path string true "Username"
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Number of items per page (default: 10)"
+// @Success 200 {object} gin.H "List of followers"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/{username}/followers [get]
+func GetFollowers(c *gin.Context) {
+	listFollows(c, "followee_id", "follower_id")
+}
+
+// GetFollowing lists the authors a user follows.
+// @Summary Get followed authors
+// @Description Retrieve the users followed by the user with the given username, newest first, with pagination support.
+// @Tags follows
+// @Produce json
+// @Param username path string true "Username"
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Number of items per page (default: 10)"
+// @Success 200 {object} gin.H "List of followed users"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/{username}/following [get]
+func GetFollowing(c *gin.Context) {
+	listFollows(c, "follower_id", "followee_id")
+}
+
+// listFollows returns the users on the other side of the follows where the
+// user from the route is in matchColumn.
+func listFollows(c *gin.Context, matchColumn, otherColumn string) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	var user models.User
+	if err := initializer.DB.First(&user, "username = ?", c.Param("username")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	follows := initializer.DB.Model(&models.Follow{}).Where(matchColumn+" = ?", user.ID)
+
+	var totalCount int64
+	if err := follows.Count(&totalCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch total count"})
+		return
+	}
+
+	users := []dto.PublicUser{}
+	if err := initializer.DB.Model(&models.User{}).
+		Select("users.id, users.username, users.bio, users.profile_pic").
+		Joins("JOIN follows ON follows."+otherColumn+" = users.id").
+		Where("follows."+matchColumn+" = ?", user.ID).
+		Order("follows.created_at DESC").
+		Offset(offset).Limit(pageSize).
+		Scan(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":       users,
+		"currentPage": page,
+		"pageSize":    pageSize,
+		"totalCount":  totalCount,
+	})
+}
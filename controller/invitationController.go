@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/auth"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
+)
+
+// CreateInvitation issues an invitation code.
+// @Summary Create an invitation code
+// @Description Issues an invitation code for signing up while registration is invite-only. The code is only shown once. Tying the code to a role requires the users:manage permission.
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param body body models.CreateInvitationRequest true "Email, role, usage limit and expiry"
+// @Success 201 {object} gin.H "Created invitation"
+// @Failure 400 {object} gin.H "Bad request, invalid request body, unknown role or expiry in the past"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Permission denied"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /invitations [post]
+func CreateInvitation(c *gin.Context) {
+	var body models.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	if body.MaxUses == 0 {
+		body.MaxUses = 1
+	}
+	if body.MaxUses < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_uses must be positive"})
+		return
+	}
+	if body.ExpiresAt != nil && body.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	invitation := models.Invitation{
+		CreatedByID: userModel.ID,
+		Email:       strings.TrimSpace(body.Email),
+		MaxUses:     body.MaxUses,
+		ExpiresAt:   body.ExpiresAt,
+	}
+
+	if body.Role != "" {
+		if !policy.Can(userModel, policy.ManageUsers) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+			return
+		}
+		var role models.Role
+		if err := initializer.DB.First(&role, "name = ?", body.Role).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+			return
+		}
+		invitation.RoleID = &role.ID
+		invitation.Role = &role
+	}
+
+	code, hash, prefix, err := auth.GenerateInvitationCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	invitation.CodeHash = hash
+	invitation.Prefix = prefix
+
+	if err := initializer.DB.Omit("Role").Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    code,
+		"details": invitation,
+	})
+}
+
+// ListInvitations lists invitation codes.
+// @Summary List invitation codes
+// @Description Lists the invitation codes issued by the logged-in user, including revoked, expired and used up ones. Users with the users:manage permission see every code.
+// @Tags invitations
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {array} models.Invitation "Invitations"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Permission denied"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /invitations [get]
+func ListInvitations(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	dbQuery := initializer.DB.Preload("Role")
+	if !policy.Can(userModel, policy.ManageUsers) {
+		dbQuery = dbQuery.Where("created_by_id = ?", userModel.ID)
+	}
+
+	invitations := []models.Invitation{}
+	if err := dbQuery.Order("created_at DESC").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeInvitation revokes an invitation code.
+// @Summary Revoke an invitation code
+// @Description Revokes an invitation code so it can no longer be used. Users can revoke their own codes; users with the users:manage permission can revoke any code.
+// @Tags invitations
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param invitationId path int true "Invitation ID"
+// @Success 200 {object} gin.H "Invitation revoked successfully"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Permission denied"
+// @Failure 404 {object} gin.H "Invitation not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /invitations/{invitationId} [delete]
+func RevokeInvitation(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	var invitation models.Invitation
+	if err := initializer.DB.Where("id = ?", c.Param("invitationId")).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if invitation.CreatedByID != userModel.ID && !policy.Can(userModel, policy.ManageUsers) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	if invitation.RevokedAt == nil {
+		if err := initializer.DB.Model(&invitation).Update("revoked_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
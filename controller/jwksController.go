@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+)
+
+// JSONWebKey is the public part of a signing key in JWK format (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// GetJWKS returns the public keys that tokens issued by the API can be verified with.
+// @Summary Get the JSON Web Key Set
+// @Description Returns the public keys, identified by kid, that are currently accepted for token verification.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} gin.H "JSON Web Key Set"
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *gin.Context) {
+	keys := make([]JSONWebKey, 0, len(initializer.VerificationKeys))
+	for kid, key := range initializer.VerificationKeys {
+		keys = append(keys, JSONWebKey{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
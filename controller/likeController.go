@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/khunaungpaing/the-blog-api/initializer"
 	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
 )
 
 // LikePost adds a like to a post.
@@ -20,6 +21,8 @@ import (
 // @Success 201 {string} string "Post liked successfully"
 // @Failure 400 {string} string "Bad request"
 // @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Post not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /posts/{postId}/likes [POST]
 func LikePost(c *gin.Context) {
@@ -44,6 +47,16 @@ func LikePost(c *gin.Context) {
 		return
 	}
 
+	var post models.Post
+	if err := initializer.DB.First(&post, postId).Error; err != nil || !policy.CanViewPost(userModel, post) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if isBlocked(post.UserID, userModel.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot like this post"})
+		return
+	}
+
 	var existingLike models.Like
 	if err := initializer.DB.Where("post_id = ? AND user_id = ?", postId, userModel.ID).First(&existingLike).Error; err == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User already liked the post"})
@@ -110,10 +123,18 @@ func UnlikePost(c *gin.Context) {
 // @Param postId path uint true "Post ID"
 // @Success 200 {object} gin.H "Likes count"
 // @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Post not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /posts/{postId}/likes [GET]
 func GetLikesForPost(c *gin.Context) {
 	postId := c.Param("postId")
+
+	var post models.Post
+	if err := initializer.DB.Scopes(visiblePosts(viewer(c))).Where("posts.id = ?", postId).First(&post).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
 	var likesCount int64
 	if err := initializer.DB.Model(&models.Like{}).Where("post_id = ?", postId).Count(&likesCount).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch likes"})
@@ -0,0 +1,363 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/auth"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is the number of recovery codes handed out at a time.
+const recoveryCodeCount = 10
+
+// EnrollTOTP starts two-factor enrolment by generating a new TOTP secret.
+// 2FA is not active until the secret is confirmed with a first code.
+// @Summary Start 2FA enrolment
+// @Description Generates a TOTP secret and returns it together with an otpauth:// provisioning URI.
+// @Tags 2fa
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {object} gin.H "Secret and provisioning URI"
+// @Failure 400 {object} gin.H "2FA already enabled"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/2fa/enroll [post]
+func EnrollTOTP(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	if userModel.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	if err := initializer.DB.Model(&userModel).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": auth.TOTPProvisioningURI(secret, userModel.Email),
+	})
+}
+
+// ConfirmTOTP activates two-factor authentication with the first code from the
+// authenticator app and returns a set of one-time recovery codes.
+// @Summary Confirm 2FA enrolment
+// @Description Activates 2FA after checking a code generated from the enrolled secret. Returns recovery codes, which are only shown once.
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param body body models.MFACodeRequest true "Code from the authenticator app"
+// @Success 200 {object} gin.H "Recovery codes"
+// @Failure 400 {object} gin.H "Invalid request, no pending enrolment or invalid code"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/2fa/confirm [post]
+func ConfirmTOTP(c *gin.Context) {
+	var body models.MFACodeRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	if userModel.TOTPEnabled || userModel.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No pending two-factor enrolment"})
+		return
+	}
+
+	step, valid := auth.ValidateTOTP(userModel.TOTPSecret, body.Code, time.Now(), userModel.TOTPLastStep)
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	var codes []string
+	err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&userModel).Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userModel.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTOTP turns off two-factor authentication after checking a current code.
+// @Summary Disable 2FA
+// @Description Disables 2FA and deletes the recovery codes. Requires a TOTP or recovery code.
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param body body models.MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} gin.H "Two-factor authentication disabled"
+// @Failure 400 {object} gin.H "Invalid request, 2FA not enabled or invalid code"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/2fa/disable [post]
+func DisableTOTP(c *gin.Context) {
+	var body models.MFACodeRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	if !userModel.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !verifySecondFactor(userModel, body.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&userModel).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", userModel.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the logged-in user.
+// @Summary Regenerate 2FA recovery codes
+// @Description Invalidates the existing recovery codes and returns a new set. Requires a TOTP code.
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param body body models.MFACodeRequest true "TOTP code"
+// @Success 200 {object} gin.H "Recovery codes"
+// @Failure 400 {object} gin.H "Invalid request, 2FA not enabled or invalid code"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var body models.MFACodeRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	if !userModel.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if !consumeTOTPCode(userModel, body.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	var codes []string
+	err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userModel.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// LoginMFA completes a login for a user with two-factor authentication.
+// @Summary Complete a 2FA login
+// @Description Exchanges the mfa_token returned by /users/login and a TOTP or recovery code for a token pair.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.MFALoginRequest true "Pending login token and code"
+// @Success 200 {object} auth.TokenPair
+// @Failure 400 {object} gin.H "Invalid request"
+// @Failure 401 {object} gin.H "Invalid token or code"
+// @Failure 429 {object} gin.H "Too many failed login attempts"
+// @Router /users/login/2fa [post]
+func LoginMFA(c *gin.Context) {
+	var body models.MFALoginRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		return
+	}
+
+	event := models.AuthEvent{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	if throttled, retryAfter := auth.IPThrottled(event.IP); throttled {
+		event.Event = models.AuthEventLoginBlocked
+		event.Reason = "ip_throttled"
+		auth.RecordAuthEvent(event)
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"message": "Too many failed login attempts, try again later"})
+		return
+	}
+
+	userID, err := auth.ParseMFAPendingToken(body.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired login"})
+		return
+	}
+
+	var user models.User
+	if err := initializer.DB.First(&user, userID).Error; err != nil || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired login"})
+		return
+	}
+	event.UserID = &user.ID
+	event.Email = user.Email
+
+	if auth.IsLocked(user) {
+		event.Event = models.AuthEventLoginBlocked
+		event.Reason = "account_locked"
+		auth.RecordAuthEvent(event)
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid code"})
+		return
+	}
+
+	if !verifySecondFactor(user, body.Code) {
+		event.Event = models.AuthEventMFAFailure
+		event.Reason = "invalid_code"
+		auth.RecordAuthEvent(event)
+		if auth.RegisterLoginFailure(user) {
+			event.Event = models.AuthEventAccountLocked
+			event.Reason = ""
+			auth.RecordAuthEvent(event)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid code"})
+		return
+	}
+
+	auth.ResetLoginFailures(user)
+	event.Event = models.AuthEventLoginSuccess
+	event.Reason = "mfa"
+	auth.RecordAuthEvent(event)
+
+	tokens, err := auth.StartSession(user, true, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Cannot create token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+func verifySecondFactor(user models.User, code string) bool {
+	return consumeTOTPCode(user, code) || consumeRecoveryCode(user, code)
+}
+
+// consumeTOTPCode checks a TOTP code and records its time step so the same code cannot be used twice.
+func consumeTOTPCode(user models.User, code string) bool {
+	step, valid := auth.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !valid {
+		return false
+	}
+	result := initializer.DB.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// consumeRecoveryCode marks a matching unused recovery code as used.
+func consumeRecoveryCode(user models.User, code string) bool {
+	result := initializer.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, auth.HashToken(auth.NormalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores a new set.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	records := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+		}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
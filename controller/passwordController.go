@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/auth"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/mailer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ForgotPassword sends a password reset link to the given email address.
+// The response is the same whether or not the address is registered.
+// @Summary Request a password reset
+// @Description Sends a single-use password reset link to the email address if it belongs to an account.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.ForgotPasswordRequest true "Account email"
+// @Success 200 {object} gin.H "Reset link sent if the account exists"
+// @Failure 400 {object} gin.H "Invalid request"
+// @Router /users/password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	var body models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		return
+	}
+
+	// Look up the account and send the link in the background, so that the
+	// response time does not reveal whether the account exists
+	go sendPasswordReset(body.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the account exists, a password reset link has been sent"})
+}
+
+// sendPasswordReset emails a new password reset link to the account with the
+// email address, if there is one.
+func sendPasswordReset(email string) {
+	var user models.User
+	if err := initializer.DB.First(&user, "email = ?", email).Error; err != nil {
+		return
+	}
+
+	token, hash, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		log.Printf("Failed to create password reset token: %v", err)
+		return
+	}
+
+	err = initializer.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link can be used
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(auth.PasswordResetTTL()),
+		}).Error
+	})
+	if err != nil {
+		log.Printf("Failed to create password reset token: %v", err)
+		return
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s/reset-password?token=%s\n\nIf you did not ask for a password reset, you can ignore this email.\n",
+			user.Username, auth.PasswordResetTTL(), os.Getenv("APP_URL"), token),
+	}
+	if err := initializer.Mailer.Send(msg); err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+}
+
+// ResetPassword sets a new password using a password reset token.
+// All existing sessions and personal access tokens of the user are revoked.
+// @Summary Reset the password
+// @Description Sets a new password using the token from the reset link. The token can only be used once. All sessions and personal access tokens of the account are revoked.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} gin.H "Password reset successfully"
+// @Failure 400 {object} gin.H "Invalid request or invalid/expired token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var body models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		return
+	}
+
+	var resetToken models.PasswordResetToken
+	err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashToken(body.Token), time.Now()).
+			First(&resetToken).Error; err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, resetToken.UserID).Error; err != nil {
+			return err
+		}
+		if err := auth.ValidatePassword(body.Password, user.Username, user.Email); err != nil {
+			return err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(body.Password), 10)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&resetToken).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("password", string(hash)).Error
+	})
+	var policyErr *auth.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		passwordPolicyError(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired reset token"})
+		return
+	}
+
+	if err := auth.RevokeAllSessions(resetToken.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to revoke sessions"})
+		return
+	}
+	if err := auth.RevokeAllPersonalAccessTokens(resetToken.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to revoke personal access tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
@@ -1,26 +1,41 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"path"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/khunaungpaing/the-blog-api/dto"
 	"github.com/khunaungpaing/the-blog-api/initializer"
 	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
+	"github.com/khunaungpaing/the-blog-api/render"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// errPostChanged is returned when a post changed, e.g. was published by the
+// scheduler, while it was being updated.
+var errPostChanged = errors.New("post changed")
+
 // CreatePost creates a new post.
 // @Summary Create a new post
-// @Description Create a new post with the provided data.
+// @Description Create a new post with the provided data. The slug is generated from the title unless one is given; a numeric suffix is added if it is taken. Scheduled posts need a publish_at in the future and are published automatically. The content is written in content_format (markdown, html or plain, default html) and rendered to sanitized HTML. Categories and tags are attached by id or by name; unknown names are created.
 // @Tags posts
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Authorization token using the Bearer scheme"
 // @Param post body dto.RequestPost true "Post data"
 // @Success 201 {object} models.Post "Successfully created post"
-// @Failure 400 {object} gin.H "Bad request, invalid request body"
+// @Failure 400 {object} gin.H "Bad request, invalid request body or unknown category or tag"
 // @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, email address not verified"
+// @Failure 409 {object} gin.H "The slug kept being taken by concurrent requests"
 // @Failure 500 {object} gin.H "Internal server error"
 // @Router /posts [post]
 func CreatePost(c *gin.Context) {
@@ -45,25 +60,90 @@ func CreatePost(c *gin.Context) {
 		return
 	}
 
+	// New posts start as a draft unless they are published or scheduled right away
+	if requestPost.Status == "" {
+		requestPost.Status = models.PostStatusDraft
+	}
+	switch requestPost.Status {
+	case models.PostStatusDraft, models.PostStatusPublished:
+	case models.PostStatusScheduled:
+		if requestPost.PublishAt == nil || !requestPost.PublishAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scheduled posts need a publish_at in the future"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New posts must be a draft, scheduled or published"})
+		return
+	}
+
+	// Unverified users can only write drafts
+	if requestPost.Status != models.PostStatusDraft && !userModel.IsVerified() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email address before publishing"})
+		return
+	}
+
 	// 3. Assign UserID to the new post
 	var newPost models.Post
 	newPost = requestPost.ToModel(newPost)
+	newPost.ID = 0
 	newPost.UserID = userModel.ID
 	newPost.User = nil
+	if newPost.Status == models.PostStatusPublished {
+		now := time.Now()
+		newPost.PublishedAt = &now
+	}
+	if newPost.Status == models.PostStatusScheduled {
+		newPost.PublishAt = requestPost.PublishAt
+	}
+	if newPost.ContentFormat == "" {
+		newPost.ContentFormat = models.ContentFormatHTML
+	}
+	if err := renderContent(&newPost); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content_format must be markdown, html or plain"})
+		return
+	}
 
-	// 4. Save the Post struct
-	if err := initializer.DB.Create(&newPost).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post", "details": err.Error()})
+	// 4. Save the Post struct under a free slug
+	err := slugTransaction(func(tx *gorm.DB) error {
+		slug, err := uniqueSlug(tx, slugBase(requestPost.Slug, requestPost.Title), 0)
+		if err != nil {
+			return err
+		}
+		newPost.Slug = slug
+		if newPost.Categories, err = resolveCategories(tx, requestPost.Categories); err != nil {
+			return err
+		}
+		if newPost.Tags, err = resolveTags(tx, requestPost.Tags); err != nil {
+			return err
+		}
+		if err := tx.Create(&newPost).Error; err != nil {
+			return err
+		}
+		_, err = recordRevision(tx, newPost.ID, userModel.ID, nil)
+		return err
+	})
+	var termErr unknownTermError
+	if errors.As(err, &termErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": termErr.Error()})
+		return
+	}
+	if isSlugConflict(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The slug was taken by another post, please try again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
 		return
 	}
 
 	// 5. Return the created Post struct
+	c.Header("ETag", etag(newPost.Version))
 	c.JSON(http.StatusCreated, newPost)
 }
 
 // GetPost retrieves a specific post by ID.
 // @Summary Retrieve a specific post
-// @Description Retrieve a specific post by its ID.
+// @Description Retrieve a specific post by its ID, with its content both as written (content, in content_format) and rendered as sanitized HTML (content_html). Drafts and archived posts are only visible to their author and editors. The ETag header holds the post's version for If-Match.
 // @Tags posts
 // @Accept json
 // @Produce json
@@ -74,25 +154,71 @@ func CreatePost(c *gin.Context) {
 func GetPost(c *gin.Context) {
 	var post models.Post
 	id := c.Param("postId")
-	if err := initializer.DB.Preload("Categories").Preload("Tags").Preload("Comments").Preload("Media").Preload("User").First(&post, id).Error; err != nil {
+	if err := postDetails(c).Where("posts.id = ?", id).First(&post).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
-	post.User.Password = ""
+	c.Header("ETag", etag(post.Version))
 	c.JSON(http.StatusOK, post)
 }
 
+// GetPostBySlug retrieves a specific post by its slug.
+// @Summary Retrieve a post by slug
+// @Description Retrieve a specific post by its slug. Old slugs of a post are redirected to its current slug with 301 Moved Permanently. Drafts and archived posts are only visible to their author and editors.
+// @Tags posts
+// @Produce json
+// @Param slug path string true "Post slug"
+// @Success 200 {object} models.Post "Found post"
+// @Success 301 {string} string "The post has moved to a new slug"
+// @Failure 404 {object} gin.H "Post not found"
+// @Router /posts/by-slug/{slug} [get]
+func GetPostBySlug(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var post models.Post
+	if err := postDetails(c).Where("posts.slug = ?", slug).First(&post).Error; err == nil {
+		c.Header("ETag", etag(post.Version))
+		c.JSON(http.StatusOK, post)
+		return
+	}
+
+	var oldSlug models.PostSlug
+	if err := initializer.DB.Where("slug = ?", slug).First(&oldSlug).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if err := initializer.DB.Scopes(visiblePosts(viewer(c))).Select("id", "slug").Where("posts.id = ?", oldSlug.PostID).First(&post).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	c.Redirect(http.StatusMovedPermanently, path.Join(path.Dir(c.Request.URL.Path), url.PathEscape(post.Slug)))
+}
+
+// postDetails prepares a query for a single post visible to the caller with
+// everything GetPost returns.
+func postDetails(c *gin.Context) *gorm.DB {
+	return initializer.DB.Scopes(visiblePosts(viewer(c))).Preload("Categories").Preload("Tags").Preload("Comments", hiddenAuthors(viewerID(c), "user_id")).Preload("Media").Preload("User", publicUserColumns)
+}
+
 // GetPosts retrieves a list of posts with pagination.
-// If the author query parameter is provided, it filters posts by that author.
+// The author, tag, category, from and to query parameters filter the list.
+// Posts and comments by users the caller has muted or blocked are left out.
+// Drafts and archived posts are only listed for their author and editors.
 // @Summary Retrieve a list of posts
-// @Description Retrieve a list of posts with pagination support.
+// @Description Retrieve a list of posts with pagination support. Drafts and archived posts are only listed for their author and editors.
 // @Tags posts
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number (default: 1)"
 // @Param pageSize query int false "Number of items per page (default: 10)"
 // @Param author query string false "Filter posts by author username"
+// @Param tag query string false "Filter posts by tag name"
+// @Param category query string false "Filter posts by category name"
+// @Param from query string false "Only posts published on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Only posts published on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param status query string false "Filter posts by status (draft, scheduled, published or archived)"
 // @Success 200 {object} gin.H "List of posts"
+// @Failure 400 {object} gin.H "Unknown status or invalid date"
 // @Failure 500 {object} gin.H "Internal server error"
 // @Router /posts [get]
 func GetPosts(c *gin.Context) {
@@ -105,26 +231,36 @@ func GetPosts(c *gin.Context) {
 	if err != nil || pageSize < 1 {
 		pageSize = 10
 	}
-	author := c.Query("author")
+	status := c.Query("status")
+	if _, known := policy.PostTransitions[status]; status != "" && !known {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown status"})
+		return
+	}
+	filters, err := postFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates (YYYY-MM-DD) or RFC 3339 times"})
+		return
+	}
 
 	// Calculate offset
 	offset := (page - 1) * pageSize
 
-	// Prepare DB query with or without author filtering
-	dbQuery := initializer.DB.Preload("Categories").Preload("Tags").Preload("Comments").Preload("Media").Preload("User").Offset(offset).Limit(pageSize)
-	if author != "" {
-		// If author is provided, filter posts by author
-		dbQuery = dbQuery.Where("user_id IN (SELECT id FROM users WHERE username = ?)", author)
+	// Prepare DB query with the requested filters
+	viewingUser := viewer(c)
+	dbQuery := initializer.DB.Model(&models.Post{}).Scopes(visiblePosts(viewingUser), hiddenAuthors(viewingUser.ID, "posts.user_id"), filters)
+	if status != "" {
+		dbQuery = dbQuery.Where("posts.status = ?", status)
 	}
 
 	// Fetch paginated posts with preloaded associations
 	var posts []models.Post
 	var totalPostsCount int64
-	if err := dbQuery.Model(&models.Post{}).Count(&totalPostsCount).Error; err != nil {
+	if err := dbQuery.Count(&totalPostsCount).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch total posts count"})
 		return
 	}
-	if err := dbQuery.Find(&posts).Error; err != nil {
+	if err := dbQuery.Preload("Categories").Preload("Tags").Preload("Comments", hiddenAuthors(viewingUser.ID, "user_id")).Preload("Media").Preload("User", publicUserColumns).
+		Offset(offset).Limit(pageSize).Find(&posts).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
 		return
 	}
@@ -142,18 +278,22 @@ func GetPosts(c *gin.Context) {
 
 // UpdatePost updates an existing post.
 // @Summary Update a post
-// @Description Update an existing post with the provided data.
+// @Description Update an existing post with the provided data. Changing the slug, or the title without giving a slug, moves the post to a new slug; the old slug redirects to it. Categories and tags, when given, replace the post's current ones and are attached by id or by name. Every update is saved as a revision.
 // @Tags posts
 // @Accept json
 // @Produce json
 // @Param postId path int true "Post ID"
 // @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param If-Match header string false "ETag of the version being updated"
 // @Param post body dto.RequestPost true "Updated post data"
 // @Success 200 {object} gin.H "Post updated successfully"
-// @Failure 400 {object} gin.H "Bad request, invalid request body"
+// @Failure 400 {object} gin.H "Bad request, invalid request body" or unknown category or tag
 // @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
 // @Failure 403 {object} gin.H "Forbidden, user is not authorized to update this post"
 // @Failure 404 {object} gin.H "Post not found"
+// @Failure 409 {object} gin.H "The status change is not allowed, or the slug kept being taken by concurrent requests"
+// @Failure 412 {object} gin.H "The post was changed in the meantime, with its current version"
+// @Failure 428 {object} gin.H "If-Match is required"
 // @Failure 500 {object} gin.H "Internal server error"
 // @Router /posts/{postId} [patch]
 func UpdatePost(c *gin.Context) {
@@ -187,21 +327,137 @@ func UpdatePost(c *gin.Context) {
 		return
 	}
 
-	if post.UserID != userModel.ID {
+	if !policy.CanUpdatePost(userModel, post) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "User is not authorized to update this post"})
 		return
 	}
+	if !checkIfMatch(c, post.Version) {
+		return
+	}
+
+	// Keep the status unless a new one is given, and only allow the transitions of the workflow
+	if requestPost.Status == "" {
+		requestPost.Status = post.Status
+	}
+	if requestPost.Status != post.Status && !policy.CanTransitionPost(post.Status, requestPost.Status) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   fmt.Sprintf("Cannot change the status of a %s post to %s", post.Status, requestPost.Status),
+			"allowed": policy.PostTransitions[post.Status],
+		})
+		return
+	}
+
+	// A scheduled post keeps its publish_at unless a new one is given
+	if requestPost.Status == models.PostStatusScheduled {
+		if requestPost.PublishAt == nil {
+			requestPost.PublishAt = post.PublishAt
+		}
+		if requestPost.PublishAt == nil || !requestPost.PublishAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scheduled posts need a publish_at in the future"})
+			return
+		}
+	}
+
+	goesLive := requestPost.Status == models.PostStatusPublished || requestPost.Status == models.PostStatusScheduled
+	if goesLive && requestPost.Status != post.Status && !userModel.IsVerified() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email address before publishing"})
+		return
+	}
 
 	// Update only the fields that are allowed to be updated
+	postID, oldSlug, oldTitle, oldFormat := post.ID, post.Slug, post.Title, post.ContentFormat
 	post = requestPost.ToModel(post)
+	post.ID = postID
+	post.Slug = oldSlug
+	if post.Status == models.PostStatusPublished && post.PublishedAt == nil {
+		now := time.Now()
+		post.PublishedAt = &now
+	}
+	post.PublishAt = nil
+	if post.Status == models.PostStatusScheduled {
+		post.PublishAt = requestPost.PublishAt
+	}
+	if post.ContentFormat == "" {
+		post.ContentFormat = oldFormat
+	}
+	if err := renderContent(&post); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content_format must be markdown, html or plain"})
+		return
+	}
 
-	// Save the updated post
-	if err := initializer.DB.Save(&post).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Save the updated post, moving it to a new slug if the slug or title changed
+	version, oldCategories, oldTags := post.Version, post.Categories, post.Tags
+	err := slugTransaction(func(tx *gorm.DB) error {
+		// Someone else, or the scheduler, may have changed the post in the meantime
+		var current models.Post
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("version").First(&current, postID).Error; err != nil {
+			return err
+		}
+		if current.Version != version {
+			return errPostChanged
+		}
+		post.Version = version + 1
+		if err := recordBaselineRevision(tx, postID); err != nil {
+			return err
+		}
+		if requestPost.Slug != "" || post.Title != oldTitle {
+			if err := changeSlug(tx, &post, oldSlug, slugBase(requestPost.Slug, post.Title)); err != nil {
+				return err
+			}
+		}
+
+		// Categories and tags are only replaced when the request lists them
+		categories, tags := oldCategories, oldTags
+		var err error
+		if requestPost.Categories != nil {
+			if categories, err = resolveCategories(tx, requestPost.Categories); err != nil {
+				return err
+			}
+		}
+		if requestPost.Tags != nil {
+			if tags, err = resolveTags(tx, requestPost.Tags); err != nil {
+				return err
+			}
+		}
+		post.Categories, post.Tags = nil, nil
+		if err := tx.Save(&post).Error; err != nil {
+			return err
+		}
+		if requestPost.Categories != nil {
+			if err := tx.Model(&post).Association("Categories").Replace(categories); err != nil {
+				return err
+			}
+		}
+		if requestPost.Tags != nil {
+			if err := tx.Model(&post).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+		}
+		post.Categories, post.Tags = categories, tags
+
+		_, err = recordRevision(tx, post.ID, userModel.ID, nil)
+		return err
+	})
+	if errors.Is(err, errPostChanged) {
+		respondStale(c, &models.Post{}, postID, "Post not found")
+		return
+	}
+	var termErr unknownTermError
+	if errors.As(err, &termErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": termErr.Error()})
+		return
+	}
+	if isSlugConflict(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The slug was taken by another post, please try again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
 		return
 	}
 
 	// Return the updated post
+	c.Header("ETag", etag(post.Version))
 	c.JSON(http.StatusOK, post)
 }
 
@@ -213,10 +469,13 @@ func UpdatePost(c *gin.Context) {
 // @Produce json
 // @Param postId path int true "Post ID"
 // @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {object} gin.H "Post deleted successfully"
 // @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
 // @Failure 403 {object} gin.H "Forbidden, user is not authorized to delete this post"
 // @Failure 404 {object} gin.H "Post not found"
+// @Failure 412 {object} gin.H "The post was changed in the meantime, with its current version"
+// @Failure 428 {object} gin.H "If-Match is required"
 // @Failure 500 {object} gin.H "Internal server error"
 // @Router /posts/{postId} [delete]
 func DeletePost(c *gin.Context) {
@@ -240,14 +499,42 @@ func DeletePost(c *gin.Context) {
 		return
 	}
 
-	if post.UserID != userModel.ID {
+	if !policy.CanDeletePost(userModel, post) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "User is not authorized to delete this post"})
 		return
 	}
+	if !checkIfMatch(c, post.Version) {
+		return
+	}
 
-	if err := initializer.DB.Delete(&post).Error; err != nil {
+	result := initializer.DB.Where("version = ?", post.Version).Delete(&post)
+	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
 		return
 	}
+	if result.RowsAffected == 0 {
+		respondStale(c, &models.Post{}, post.ID, "Post not found")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
+
+// renderContent renders the content of a post as sanitized HTML. HTML sources
+// are replaced with the sanitized version too, so that clients showing the
+// content as it is stay safe.
+func renderContent(post *models.Post) error {
+	rendered, err := render.HTML(post.ContentFormat, post.Content)
+	if err != nil {
+		return err
+	}
+	post.ContentHTML = rendered
+	if post.ContentFormat == models.ContentFormatHTML {
+		post.Content = rendered
+	}
+	return nil
+}
+
+// publicUserColumns limits a preloaded post author to the fields anyone may see.
+func publicUserColumns(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "created_at", "updated_at", "username", "bio", "profile_pic")
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
+	"gorm.io/gorm"
+)
+
+// postDate is the public date of a post: when it was published, so that a
+// post drafted long ago and published today counts as new.
+const postDate = "COALESCE(posts.published_at, posts.created_at)"
+
+// hiddenAuthors returns a query scope that removes rows written by users the
+// viewer has muted or blocked. column names the author column of the queried
+// table, e.g. "posts.user_id". Anonymous viewers (ID 0) see everything.
+func hiddenAuthors(viewerID uint, column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerID == 0 {
+			return db
+		}
+		muted := initializer.DB.Model(&models.Mute{}).Select("muted_id").Where("muter_id = ?", viewerID)
+		blocked := initializer.DB.Model(&models.Block{}).Select("blocked_id").Where("blocker_id = ?", viewerID)
+		return db.Where(column+" NOT IN (?)", muted).Where(column+" NOT IN (?)", blocked)
+	}
+}
+
+// isBlocked reports whether the owner has blocked the user.
+func isBlocked(ownerID, userID uint) bool {
+	var count int64
+	initializer.DB.Model(&models.Block{}).Where("blocker_id = ? AND blocked_id = ?", ownerID, userID).Count(&count)
+	return count > 0
+}
+
+// visiblePosts returns a query scope that hides drafts and archived posts
+// from everyone but their author and editors, see policy.CanViewPost.
+func visiblePosts(viewer models.User) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if policy.Can(viewer, policy.UpdateAnyPost) {
+			return db
+		}
+		if viewer.ID == 0 {
+			return db.Where("posts.status = ?", models.PostStatusPublished)
+		}
+		return db.Where("(posts.status = ? OR posts.user_id = ?)", models.PostStatusPublished, viewer.ID)
+	}
+}
+
+// postFilters returns a query scope for the author, tag, category, from and
+// to query parameters. Tags and categories match by name, ignoring case. The
+// date range applies to the publication date, or the creation date of posts
+// that were never published.
+func postFilters(c *gin.Context) (func(db *gorm.DB) *gorm.DB, error) {
+	from, err := parseDateParam(c.Query("from"), false)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseDateParam(c.Query("to"), true)
+	if err != nil {
+		return nil, err
+	}
+	author, tag, category := c.Query("author"), c.Query("tag"), c.Query("category")
+
+	return func(db *gorm.DB) *gorm.DB {
+		if author != "" {
+			db = db.Where("posts.user_id IN (SELECT id FROM users WHERE username = ?)", author)
+		}
+		if tag != "" {
+			db = db.Where("posts.id IN (SELECT post_tags.post_id FROM post_tags JOIN tags ON tags.id = post_tags.tag_id WHERE lower(tags.name) = lower(?) AND tags.deleted_at IS NULL)", tag)
+		}
+		if category != "" {
+			db = db.Where("posts.id IN (SELECT post_categories.post_id FROM post_categories JOIN categories ON categories.id = post_categories.category_id WHERE lower(categories.name) = lower(?) AND categories.deleted_at IS NULL)", category)
+		}
+		if from != nil {
+			db = db.Where(postDate+" >= ?", *from)
+		}
+		if to != nil {
+			db = db.Where(postDate+" <= ?", *to)
+		}
+		return db
+	}, nil
+}
+
+// parseDateParam parses a date (2006-01-02) or an RFC 3339 time. A date at
+// the end of a range includes the whole day.
+func parseDateParam(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if end {
+		t = t.Add(24*time.Hour - time.Microsecond)
+	}
+	return &t, nil
+}
+
+// viewerID returns the ID of the authenticated user, or 0 for anonymous requests.
+func viewerID(c *gin.Context) uint {
+	return viewer(c).ID
+}
+
+// viewer returns the authenticated user, or a zero User for anonymous requests.
+func viewer(c *gin.Context) models.User {
+	user, exist := c.Get("user")
+	if !exist {
+		return models.User{}
+	}
+	userModel, _ := user.(models.User)
+	return userModel
+}
@@ -0,0 +1,297 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/dto"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
+	"github.com/khunaungpaing/the-blog-api/taxonomy"
+	"github.com/khunaungpaing/the-blog-api/textdiff"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListPostRevisions lists the revisions of a post.
+// @Summary List post revisions
+// @Description Lists the revisions of a post, newest first. A revision is saved every time the post is created, updated or restored. Only users who may edit the post can see its history.
+// @Tags revisions
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {array} models.PostRevision "Revisions"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user is not authorized to update this post"
+// @Failure 404 {object} gin.H "Post not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /posts/{postId}/revisions [get]
+func ListPostRevisions(c *gin.Context) {
+	post, _, ok := revisionPost(c)
+	if !ok {
+		return
+	}
+
+	revisions := []models.PostRevision{}
+	if err := initializer.DB.
+		Preload("Author", publicUserColumns).
+		Where("post_id = ?", post.ID).
+		Order("number DESC").
+		Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetPostRevision gets a single revision of a post.
+// @Summary Get a post revision
+// @Description Retrieve a revision of a post by its number.
+// @Tags revisions
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Param revision path int true "Revision number"
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {object} models.PostRevision "Revision"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user is not authorized to update this post"
+// @Failure 404 {object} gin.H "Post or revision not found"
+// @Router /posts/{postId}/revisions/{revision} [get]
+func GetPostRevision(c *gin.Context) {
+	post, _, ok := revisionPost(c)
+	if !ok {
+		return
+	}
+
+	var revision models.PostRevision
+	if err := initializer.DB.Preload("Author", publicUserColumns).
+		Where("post_id = ? AND number = ?", post.ID, c.Param("revision")).
+		First(&revision).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// DiffPostRevisions compares two revisions of a post.
+// @Summary Diff two post revisions
+// @Description Shows what changed between two revisions of a post. The content is compared line by line, or word by word with mode=word; the title is always compared word by word. Categories and tags are listed as added and removed.
+// @Tags revisions
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Param from query int true "Revision number to compare from"
+// @Param to query int true "Revision number to compare to"
+// @Param mode query string false "line (default) or word"
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {object} dto.RevisionDiff "Differences"
+// @Failure 400 {object} gin.H "Invalid revision numbers or mode"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user is not authorized to update this post"
+// @Failure 404 {object} gin.H "Post or revision not found"
+// @Router /posts/{postId}/revisions/diff [get]
+func DiffPostRevisions(c *gin.Context) {
+	post, _, ok := revisionPost(c)
+	if !ok {
+		return
+	}
+
+	from, fromErr := strconv.Atoi(c.Query("from"))
+	to, toErr := strconv.Atoi(c.Query("to"))
+	if fromErr != nil || toErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be revision numbers"})
+		return
+	}
+	mode := c.DefaultQuery("mode", "line")
+	if mode != "line" && mode != "word" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be line or word"})
+		return
+	}
+
+	var revisions []models.PostRevision
+	if err := initializer.DB.Where("post_id = ? AND number IN ?", post.ID, []int{from, to}).Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+	byNumber := map[int]models.PostRevision{}
+	for _, revision := range revisions {
+		byNumber[revision.Number] = revision
+	}
+	older, olderFound := byNumber[from]
+	newer, newerFound := byNumber[to]
+	if !olderFound || !newerFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	diff := dto.RevisionDiff{
+		From:       from,
+		To:         to,
+		Mode:       mode,
+		Title:      textdiff.Words(older.Title, newer.Title),
+		Categories: termChanges(older.Categories, newer.Categories),
+		Tags:       termChanges(older.Tags, newer.Tags),
+	}
+	if mode == "word" {
+		diff.Content = textdiff.Words(older.Content, newer.Content)
+	} else {
+		diff.Content = textdiff.Lines(older.Content, newer.Content)
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// RestorePostRevision restores an old revision of a post.
+// @Summary Restore a post revision
+// @Description Sets the title, content, content format, categories and tags of the post back to those of a revision and saves the result as a new revision; the history itself is never changed. The status is kept. Categories and tags that were merged since are replaced by the one they were merged into, those that were deleted are skipped, and a changed title moves the post to a new slug like an update does.
+// @Tags revisions
+// @Produce json
+// @Param postId path int true "Post ID"
+// @Param revision path int true "Revision number"
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param If-Match header string false "ETag of the version of the post being replaced"
+// @Success 201 {object} models.PostRevision "The new revision"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user is not authorized to update this post"
+// @Failure 404 {object} gin.H "Post or revision not found"
+// @Failure 412 {object} gin.H "The post was changed in the meantime, with its current version"
+// @Failure 428 {object} gin.H "If-Match is required"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /posts/{postId}/revisions/{revision}/restore [post]
+func RestorePostRevision(c *gin.Context) {
+	post, userModel, ok := revisionPost(c)
+	if !ok {
+		return
+	}
+
+	var revision models.PostRevision
+	if err := initializer.DB.Where("post_id = ? AND number = ?", post.ID, c.Param("revision")).First(&revision).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	if !checkIfMatch(c, post.Version) {
+		return
+	}
+
+	var restored models.PostRevision
+	err := slugTransaction(func(tx *gorm.DB) error {
+		// Someone else may have changed the post since it was loaded
+		var current models.Post
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, post.ID).Error; err != nil {
+			return err
+		}
+		if current.Version != post.Version {
+			return errPostChanged
+		}
+		if revision.Title != current.Title {
+			if err := changeSlug(tx, &current, current.Slug, slugBase("", revision.Title)); err != nil {
+				return err
+			}
+		}
+		current.Content, current.ContentFormat = revision.Content, revision.ContentFormat
+		if err := renderContent(&current); err != nil {
+			return err
+		}
+		if err := tx.Model(&current).Updates(map[string]interface{}{
+			"title":          revision.Title,
+			"content":        current.Content,
+			"content_format": current.ContentFormat,
+			"content_html":   current.ContentHTML,
+			"slug":           current.Slug,
+			"version":        post.Version + 1,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Categories and tags merged since then are restored as their merge target
+		categoryIDs, err := taxonomy.Successors(tx, taxonomy.Categories, termIDs(revision.Categories))
+		if err != nil {
+			return err
+		}
+		var categories []models.Category
+		if err := tx.Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&current).Association("Categories").Replace(categories); err != nil {
+			return err
+		}
+		tagIDs, err := taxonomy.Successors(tx, taxonomy.Tags, termIDs(revision.Tags))
+		if err != nil {
+			return err
+		}
+		var tags []models.Tag
+		if err := tx.Where("id IN ?", tagIDs).Find(&tags).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&current).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+
+		restored, err = recordRevision(tx, post.ID, userModel.ID, &revision.Number)
+		return err
+	})
+	if errors.Is(err, errPostChanged) {
+		respondStale(c, &models.Post{}, post.ID, "Post not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	c.Header("ETag", etag(post.Version+1))
+	c.JSON(http.StatusCreated, restored)
+}
+
+// revisionPost loads the post of a revision request and checks that the user
+// may edit it, which is required to see its history. It responds with an
+// error and returns false otherwise.
+func revisionPost(c *gin.Context) (models.Post, models.User, bool) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return models.Post{}, models.User{}, false
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return models.Post{}, models.User{}, false
+	}
+
+	var post models.Post
+	if err := initializer.DB.Where("id = ?", c.Param("postId")).First(&post).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return post, userModel, false
+	}
+	if !policy.CanUpdatePost(userModel, post) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User is not authorized to update this post"})
+		return post, userModel, false
+	}
+	return post, userModel, true
+}
+
+// termChanges lists the categories or tags that are only in one of two revisions.
+func termChanges(older, newer []models.RevisionTerm) dto.TermChanges {
+	changes := dto.TermChanges{Added: []models.RevisionTerm{}, Removed: []models.RevisionTerm{}}
+	inOld := map[uint]bool{}
+	for _, term := range older {
+		inOld[term.ID] = true
+	}
+	inNew := map[uint]bool{}
+	for _, term := range newer {
+		inNew[term.ID] = true
+		if !inOld[term.ID] {
+			changes.Added = append(changes.Added, term)
+		}
+	}
+	for _, term := range older {
+		if !inNew[term.ID] {
+			changes.Removed = append(changes.Removed, term)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// snapshotPost builds the next revision of a post from its current state. The
+// post row is locked so that concurrent edits get consecutive numbers.
+func snapshotPost(tx *gorm.DB, postID uint) (models.PostRevision, models.Post, error) {
+	var post models.Post
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&post, postID).Error; err != nil {
+		return models.PostRevision{}, post, err
+	}
+	var categories []models.Category
+	if err := tx.Model(&post).Association("Categories").Find(&categories); err != nil {
+		return models.PostRevision{}, post, err
+	}
+	var tags []models.Tag
+	if err := tx.Model(&post).Association("Tags").Find(&tags); err != nil {
+		return models.PostRevision{}, post, err
+	}
+	var last int
+	if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", postID).
+		Select("COALESCE(MAX(number), 0)").Scan(&last).Error; err != nil {
+		return models.PostRevision{}, post, err
+	}
+
+	revision := models.PostRevision{
+		PostID:        post.ID,
+		Number:        last + 1,
+		Title:         post.Title,
+		Content:       post.Content,
+		ContentFormat: post.ContentFormat,
+		Categories:    make([]models.RevisionTerm, 0, len(categories)),
+		Tags:          make([]models.RevisionTerm, 0, len(tags)),
+	}
+	for _, category := range categories {
+		revision.Categories = append(revision.Categories, models.RevisionTerm{ID: category.ID, Name: category.Name})
+	}
+	for _, tag := range tags {
+		revision.Tags = append(revision.Tags, models.RevisionTerm{ID: tag.ID, Name: tag.Name})
+	}
+	return revision, post, nil
+}
+
+// recordRevision saves the current state of a post as a new revision made by
+// the author. restoredFrom is the revision it restores, if any.
+func recordRevision(tx *gorm.DB, postID, authorID uint, restoredFrom *int) (models.PostRevision, error) {
+	revision, _, err := snapshotPost(tx, postID)
+	if err != nil {
+		return revision, err
+	}
+	revision.AuthorID = authorID
+	revision.RestoredFrom = restoredFrom
+	return revision, tx.Create(&revision).Error
+}
+
+// recordBaselineRevision saves the current state of a post that has no
+// revisions yet because it was written before they existed, so that its
+// first edit can be undone.
+func recordBaselineRevision(tx *gorm.DB, postID uint) error {
+	revision, post, err := snapshotPost(tx, postID)
+	if err != nil || revision.Number > 1 {
+		return err
+	}
+	revision.AuthorID = post.UserID
+	revision.CreatedAt = post.UpdatedAt
+	return tx.Create(&revision).Error
+}
+
+// termIDs returns the IDs of revision categories or tags.
+func termIDs(terms []models.RevisionTerm) []uint {
+	ids := make([]uint, len(terms))
+	for i, term := range terms {
+		ids[i] = term.ID
+	}
+	return ids
+}
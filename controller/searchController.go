@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/dto"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+)
+
+// searchHeadlineOptions configures the ts_headline snippets.
+const searchHeadlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`
+
+// SearchPosts searches posts by relevance.
+// Posts by users the caller has muted or blocked are left out.
+// Drafts and archived posts are only found by their author and editors.
+// @Summary Search posts
+// @Description Full-text search over the title, tags, categories and content of posts, ranked by relevance with matches in the title weighing the most. q supports "quoted phrases", OR and -excluded words. Every result comes with its title and content snippets highlighted with <mark>. Drafts and archived posts are only found by their author and editors.
+// @Tags posts
+// @Produce json
+// @Param q query string true "Search terms"
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Number of items per page (default: 10)"
+// @Param author query string false "Filter posts by author username"
+// @Param tag query string false "Filter posts by tag name"
+// @Param category query string false "Filter posts by category name"
+// @Param from query string false "Only posts published on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Only posts published on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Success 200 {object} gin.H "Search results"
+// @Failure 400 {object} gin.H "Missing search terms or invalid date"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /posts/search [get]
+func SearchPosts(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+	filters, err := postFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be dates (YYYY-MM-DD) or RFC 3339 times"})
+		return
+	}
+
+	viewingUser := viewer(c)
+	dbQuery := initializer.DB.Model(&models.Post{}).
+		Joins("CROSS JOIN websearch_to_tsquery('english', ?) AS query", q).
+		Scopes(visiblePosts(viewingUser), hiddenAuthors(viewingUser.ID, "posts.user_id"), filters).
+		Where("posts.search_vector @@ query")
+
+	var totalCount int64
+	if err := dbQuery.Count(&totalCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts"})
+		return
+	}
+
+	// Titles are escaped and the content is taken from the sanitized HTML
+	// without its tags, so that the highlights are safe to show as HTML
+	var hits []struct {
+		ID             uint
+		Rank           float64
+		TitleHighlight string
+		Snippet        string
+	}
+	if err := dbQuery.Select(
+		"posts.id, ts_rank(posts.search_vector, query) AS rank, "+
+			"ts_headline('english', replace(replace(replace(posts.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_highlight, "+
+			"ts_headline('english', regexp_replace(posts.content_html, '<[^>]*>', ' ', 'g'), query, ?) AS snippet",
+		searchHeadlineOptions,
+	).Order("rank DESC, posts.id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Scan(&hits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts"})
+		return
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var posts []models.Post
+	if err := initializer.DB.Preload("Categories").Preload("Tags").Preload("Media").Preload("User", publicUserColumns).
+		Where("id IN ?", ids).Find(&posts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts"})
+		return
+	}
+	byID := map[uint]models.Post{}
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+
+	results := make([]dto.PostSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, dto.PostSearchResult{
+			Post:           byID[hit.ID],
+			Rank:           hit.Rank,
+			TitleHighlight: hit.TitleHighlight,
+			Snippet:        hit.Snippet,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":     results,
+		"currentPage": page,
+		"pageSize":    pageSize,
+		"totalCount":  totalCount,
+	})
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/auth"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+)
+
+// ListSessions lists the active sessions of the logged-in user.
+// @Summary List sessions
+// @Description Lists the devices the logged-in user is logged in on, with user agent, IP address, creation and last-seen times. The session making the request is marked as current.
+// @Tags sessions
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {array} models.Session "Active sessions"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/sessions [get]
+func ListSessions(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	sessions := []models.Session{}
+	if err := initializer.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userModel.ID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	currentID := c.GetUint("session_id")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeUserSession logs a single device out.
+// @Summary Revoke a session
+// @Description Revokes one of the logged-in user's sessions together with its refresh tokens. Access tokens of the session stop working immediately.
+// @Tags sessions
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param sessionId path int true "Session ID"
+// @Success 200 {object} gin.H "Session revoked successfully"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 404 {object} gin.H "Session not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/sessions/{sessionId} [delete]
+func RevokeUserSession(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	var session models.Session
+	if err := initializer.DB.Where("id = ? AND user_id = ?", c.Param("sessionId"), userModel.ID).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if err := auth.RevokeSession(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
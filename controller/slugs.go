@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/slugify"
+	"gorm.io/gorm"
+)
+
+// slugAttempts is how many times a write that picks a slug is tried before
+// giving up on concurrent requests taking the same slug.
+const slugAttempts = 5
+
+// slugBase returns the slug a post asks for: the client's slug if it has any
+// usable characters, otherwise one generated from the title.
+func slugBase(requested, title string) string {
+	if base := slugify.Make(requested); base != "" {
+		return base
+	}
+	if base := slugify.Make(title); base != "" {
+		return base
+	}
+	return "post"
+}
+
+// uniqueSlug returns base, or base with the lowest free numeric suffix if it
+// is taken. Slugs of deleted posts and old slugs of other posts count as
+// taken so that existing links never start pointing at a different post.
+func uniqueSlug(tx *gorm.DB, base string, postID uint) (string, error) {
+	var taken []string
+	if err := tx.Unscoped().Model(&models.Post{}).
+		Where("(slug = ? OR slug LIKE ?) AND id <> ?", base, base+"-%", postID).
+		Pluck("slug", &taken).Error; err != nil {
+		return "", err
+	}
+	var history []string
+	if err := tx.Model(&models.PostSlug{}).
+		Where("(slug = ? OR slug LIKE ?) AND post_id <> ?", base, base+"-%", postID).
+		Pluck("slug", &history).Error; err != nil {
+		return "", err
+	}
+
+	used := map[string]bool{}
+	for _, slug := range append(taken, history...) {
+		used[slug] = true
+	}
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
+
+// changeSlug moves the post to a new slug and keeps the old one so that it
+// can be redirected.
+func changeSlug(tx *gorm.DB, post *models.Post, oldSlug, base string) error {
+	slug, err := uniqueSlug(tx, base, post.ID)
+	if err != nil {
+		return err
+	}
+	post.Slug = slug
+	if slug == oldSlug {
+		return nil
+	}
+
+	// The post may be moving back to one of its own old slugs
+	if err := tx.Where("post_id = ? AND slug = ?", post.ID, slug).Delete(&models.PostSlug{}).Error; err != nil {
+		return err
+	}
+	if oldSlug == "" {
+		return nil
+	}
+	return tx.Create(&models.PostSlug{PostID: post.ID, Slug: oldSlug}).Error
+}
+
+// slugTransaction runs fn in a transaction. uniqueSlug cannot see slugs that
+// concurrent requests are about to take, so when the unique index on the slug
+// rejects the write, fn is run again and picks the next free slug.
+func slugTransaction(fn func(tx *gorm.DB) error) error {
+	var err error
+	for attempt := 0; attempt < slugAttempts; attempt++ {
+		if err = initializer.DB.Transaction(fn); !isSlugConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// isSlugConflict reports whether err is a unique violation on a post slug or
+// an old slug.
+func isSlugConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && strings.Contains(pgErr.ConstraintName, "slug")
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/dto"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/taxonomy"
+)
+
+// ListTags lists every tag with the number of published posts filed under it.
+// @Summary List tags
+// @Description Lists every tag, sorted by name, with the number of published posts filed under it.
+// @Tags tags
+// @Produce json
+// @Success 200 {array} dto.Term "Tags"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /tags [get]
+func ListTags(c *gin.Context) {
+	listTerms(c, taxonomy.Tags)
+}
+
+// GetTag retrieves a single tag.
+// @Summary Get a tag
+// @Description Retrieves a tag with the number of published posts filed under it.
+// @Tags tags
+// @Produce json
+// @Param tagId path int true "Tag ID"
+// @Success 200 {object} dto.Term "Tag"
+// @Failure 404 {object} gin.H "Tag not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /tags/{tagId} [get]
+func GetTag(c *gin.Context) {
+	respondTerm(c, http.StatusOK, taxonomy.Tags, c.Param("tagId"))
+}
+
+// CreateTag creates a new tag.
+// Names are unique regardless of case and spacing.
+// @Summary Create a tag
+// @Description Creates a new tag. Names are unique regardless of case and extra whitespace.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param tag body dto.RequestTerm true "Tag data"
+// @Success 201 {object} dto.Term "Successfully created tag"
+// @Failure 400 {object} gin.H "Bad request, invalid request body or blank name"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user may not manage tags"
+// @Failure 409 {object} gin.H "A tag with this name already exists, with its ID"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /tags [post]
+func CreateTag(c *gin.Context) {
+	var request dto.RequestTerm
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	name := taxonomy.CleanName(request.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	tag := models.Tag{Name: name, NormalizedName: taxonomy.Normalize(name)}
+	if request.Description != nil {
+		tag.Description = *request.Description
+	}
+	if err := initializer.DB.Clauses(taxonomy.OnNameConflict).Create(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
+		return
+	}
+	if tag.ID == 0 {
+		existingID, _ := nameTaken(taxonomy.Tags, tag.NormalizedName, 0)
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists", "id": existingID})
+		return
+	}
+
+	respondTerm(c, http.StatusCreated, taxonomy.Tags, tag.ID)
+}
+
+// UpdateTag renames a tag or changes its description.
+// @Summary Update a tag
+// @Description Renames a tag or changes its description. Posts filed under the tag keep it. Renaming it to the name of another tag is refused; merge them instead.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param tagId path int true "Tag ID"
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param tag body dto.RequestTerm true "New name and/or description"
+// @Success 200 {object} dto.Term "Successfully updated tag"
+// @Failure 400 {object} gin.H "Bad request, invalid request body or blank name"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user may not manage tags"
+// @Failure 404 {object} gin.H "Tag not found"
+// @Failure 409 {object} gin.H "Another tag has this name, with its ID"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /tags/{tagId} [patch]
+func UpdateTag(c *gin.Context) {
+	updateTerm(c, taxonomy.Tags, "tagId")
+}
+
+// DeleteTag deletes a tag and removes it from its posts.
+// @Summary Delete a tag
+// @Description Deletes a tag and removes it from every post filed under it. The posts themselves are kept.
+// @Tags tags
+// @Produce json
+// @Param tagId path int true "Tag ID"
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {object} gin.H "Successfully deleted tag"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user may not manage tags"
+// @Failure 404 {object} gin.H "Tag not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /tags/{tagId} [delete]
+func DeleteTag(c *gin.Context) {
+	deleteTerm(c, taxonomy.Tags, "tagId")
+}
+
+// MergeTags merges other tags into a tag.
+// @Summary Merge tags
+// @Description Moves the posts of the source tags to this tag and deletes the sources.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param tagId path int true "ID of the tag to merge into"
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param merge body dto.RequestMerge true "IDs of the tags to merge"
+// @Success 200 {object} dto.Term "The merged tag"
+// @Failure 400 {object} gin.H "Bad request, no sources or the tag itself among them"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 403 {object} gin.H "Forbidden, user may not manage tags"
+// @Failure 404 {object} gin.H "Tag not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /tags/{tagId}/merge [post]
+func MergeTags(c *gin.Context) {
+	mergeTerms(c, taxonomy.Tags, "tagId")
+}
@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/dto"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/taxonomy"
+	"gorm.io/gorm"
+)
+
+// unknownTermError is returned when a post asks for a category or tag that
+// does not exist.
+type unknownTermError string
+
+func (e unknownTermError) Error() string {
+	return string(e)
+}
+
+// resolveCategories looks up the categories a post asks for by ID or by name,
+// creating the named ones that do not exist yet.
+func resolveCategories(tx *gorm.DB, requested []dto.RequestCategory) ([]models.Category, error) {
+	categories := []models.Category{}
+	seen := map[uint]bool{}
+	for _, r := range requested {
+		var category models.Category
+		name := taxonomy.CleanName(r.Name)
+		switch {
+		case r.ID != 0:
+			if err := tx.First(&category, r.ID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, unknownTermError(fmt.Sprintf("Category %d does not exist", r.ID))
+			} else if err != nil {
+				return nil, err
+			}
+		case name != "":
+			category = models.Category{Name: name, NormalizedName: taxonomy.Normalize(name), Description: r.Description}
+			if err := tx.Clauses(taxonomy.OnNameConflict).Create(&category).Error; err != nil {
+				return nil, err
+			}
+			if category.ID == 0 {
+				if err := tx.Where("normalized_name = ?", category.NormalizedName).First(&category).Error; err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, unknownTermError("Categories need an id or a name")
+		}
+		if !seen[category.ID] {
+			seen[category.ID] = true
+			categories = append(categories, category)
+		}
+	}
+	return categories, nil
+}
+
+// resolveTags looks up the tags a post asks for by ID or by name, creating
+// the named ones that do not exist yet.
+func resolveTags(tx *gorm.DB, requested []dto.RequestTag) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	seen := map[uint]bool{}
+	for _, r := range requested {
+		var tag models.Tag
+		name := taxonomy.CleanName(r.Name)
+		switch {
+		case r.ID != 0:
+			if err := tx.First(&tag, r.ID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, unknownTermError(fmt.Sprintf("Tag %d does not exist", r.ID))
+			} else if err != nil {
+				return nil, err
+			}
+		case name != "":
+			tag = models.Tag{Name: name, NormalizedName: taxonomy.Normalize(name), Description: r.Description}
+			if err := tx.Clauses(taxonomy.OnNameConflict).Create(&tag).Error; err != nil {
+				return nil, err
+			}
+			if tag.ID == 0 {
+				if err := tx.Where("normalized_name = ?", tag.NormalizedName).First(&tag).Error; err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, unknownTermError("Tags need an id or a name")
+		}
+		if !seen[tag.ID] {
+			seen[tag.ID] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// termQuery selects categories or tags as dto.Term, counting the published
+// posts filed under them.
+func termQuery(kind taxonomy.Kind) *gorm.DB {
+	table, join := kind.Table, kind.JoinTable
+	return initializer.DB.Model(kind.New()).Select(
+		table+".id, "+table+".name, "+table+".description, "+table+".created_at, "+table+".updated_at, "+
+			"(SELECT count(*) FROM "+join+" JOIN posts ON posts.id = "+join+".post_id"+
+			" WHERE "+join+"."+kind.JoinColumn+" = "+table+".id AND posts.status = ? AND posts.deleted_at IS NULL) AS post_count",
+		models.PostStatusPublished,
+	)
+}
+
+// listTerms responds with every category or tag, sorted by name.
+func listTerms(c *gin.Context, kind taxonomy.Kind) {
+	terms := []dto.Term{}
+	if err := termQuery(kind).Order(kind.Table + ".normalized_name").Find(&terms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + kind.Table})
+		return
+	}
+	c.JSON(http.StatusOK, terms)
+}
+
+// respondTerm responds with a single category or tag.
+func respondTerm(c *gin.Context, status int, kind taxonomy.Kind, id interface{}) {
+	terms := []dto.Term{}
+	if err := termQuery(kind).Where(kind.Table+".id = ?", id).Find(&terms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + kind.Table})
+		return
+	}
+	if len(terms) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": kind.Noun + " not found"})
+		return
+	}
+	c.JSON(status, terms[0])
+}
+
+// findTerm returns the ID of the category or tag in the route parameter, or
+// responds with 404.
+func findTerm(c *gin.Context, kind taxonomy.Kind, param string) (uint, bool) {
+	var ids []uint
+	if err := initializer.DB.Model(kind.New()).Where("id = ?", c.Param(param)).Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": kind.Noun + " not found"})
+		return 0, false
+	}
+	return ids[0], true
+}
+
+// nameTaken returns the ID of another category or tag with the same
+// normalized name.
+func nameTaken(kind taxonomy.Kind, normalized string, exceptID uint) (uint, bool) {
+	var ids []uint
+	initializer.DB.Model(kind.New()).Where("normalized_name = ? AND id <> ?", normalized, exceptID).Pluck("id", &ids)
+	if len(ids) == 0 {
+		return 0, false
+	}
+	return ids[0], true
+}
+
+// updateTerm renames a category or tag or changes its description.
+func updateTerm(c *gin.Context, kind taxonomy.Kind, param string) {
+	id, ok := findTerm(c, kind, param)
+	if !ok {
+		return
+	}
+	var request dto.RequestTerm
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if request.Name != "" {
+		name := taxonomy.CleanName(request.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name must not be blank"})
+			return
+		}
+		normalized := taxonomy.Normalize(name)
+		if otherID, taken := nameTaken(kind, normalized, id); taken {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": fmt.Sprintf("Another %s is already named %q, merge them instead", strings.ToLower(kind.Noun), name),
+				"id":    otherID,
+			})
+			return
+		}
+		updates["name"] = name
+		updates["normalized_name"] = normalized
+	}
+	if request.Description != nil {
+		updates["description"] = *request.Description
+	}
+
+	if len(updates) > 0 {
+		if err := initializer.DB.Model(kind.New()).Where("id = ?", id).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update " + strings.ToLower(kind.Noun)})
+			return
+		}
+	}
+	respondTerm(c, http.StatusOK, kind, id)
+}
+
+// deleteTerm deletes a category or tag and detaches it from its posts.
+func deleteTerm(c *gin.Context, kind taxonomy.Kind, param string) {
+	id, ok := findTerm(c, kind, param)
+	if !ok {
+		return
+	}
+
+	err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM "+kind.JoinTable+" WHERE "+kind.JoinColumn+" = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(kind.New()).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete " + strings.ToLower(kind.Noun)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": kind.Noun + " deleted successfully"})
+}
+
+// mergeTerms merges the categories or tags listed in the request into the
+// one in the route.
+func mergeTerms(c *gin.Context, kind taxonomy.Kind, param string) {
+	targetID, ok := findTerm(c, kind, param)
+	if !ok {
+		return
+	}
+	var request dto.RequestMerge
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source_ids must list at least one ID"})
+		return
+	}
+
+	sourceIDs := []uint{}
+	seen := map[uint]bool{}
+	for _, id := range request.SourceIDs {
+		if id == targetID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a " + strings.ToLower(kind.Noun) + " into itself"})
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+	var found int64
+	if err := initializer.DB.Model(kind.New()).Where("id IN ?", sourceIDs).Count(&found).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge " + kind.Table})
+		return
+	}
+	if found != int64(len(sourceIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": kind.Noun + " not found"})
+		return
+	}
+
+	if err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+		return taxonomy.Merge(tx, kind, targetID, sourceIDs)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge " + kind.Table})
+		return
+	}
+	respondTerm(c, http.StatusOK, kind, targetID)
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/auth"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
+)
+
+// CreatePersonalAccessToken creates a named personal access token for the logged-in user.
+// The token is only returned once; afterwards only its prefix can be seen.
+// @Summary Create a personal access token
+// @Description Creates a personal access token with the given scopes and optional expiry. The token is only shown in this response.
+// @Tags tokens
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param body body models.CreateTokenRequest true "Token name, scopes and expiry"
+// @Success 201 {object} gin.H "Created token"
+// @Failure 400 {object} gin.H "Bad request, invalid request body, unknown scope or expiry in the past"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/tokens [post]
+func CreatePersonalAccessToken(c *gin.Context) {
+	var body models.CreateTokenRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	scopes := make([]string, 0, len(body.Scopes))
+	for _, scope := range body.Scopes {
+		if _, known := policy.Scopes[scope]; !known {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+		if !policy.HasScope(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required"})
+		return
+	}
+	sort.Strings(scopes)
+
+	if body.ExpiresAt != nil && body.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	token, hash, prefix, err := auth.GeneratePersonalAccessToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	pat := models.PersonalAccessToken{
+		UserID:      userModel.ID,
+		Name:        body.Name,
+		TokenHash:   hash,
+		Prefix:      prefix,
+		Scopes:      strings.Join(scopes, ","),
+		MFAVerified: c.GetBool("mfa_verified"),
+		ExpiresAt:   body.ExpiresAt,
+	}
+	if err := initializer.DB.Create(&pat).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":   token,
+		"details": pat,
+	})
+}
+
+// ListPersonalAccessTokens lists the personal access tokens of the logged-in user.
+// @Summary List personal access tokens
+// @Description Lists the personal access tokens of the logged-in user, including revoked and expired ones.
+// @Tags tokens
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {array} models.PersonalAccessToken "Tokens"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/tokens [get]
+func ListPersonalAccessTokens(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	var tokens []models.PersonalAccessToken
+	if err := initializer.DB.Where("user_id = ?", userModel.ID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RevokePersonalAccessToken revokes one of the logged-in user's personal access tokens.
+// @Summary Revoke a personal access token
+// @Description Revokes the specified personal access token of the logged-in user.
+// @Tags tokens
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Param tokenId path int true "Token ID"
+// @Success 200 {object} gin.H "Token revoked successfully"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 404 {object} gin.H "Token not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/tokens/{tokenId} [delete]
+func RevokePersonalAccessToken(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	var pat models.PersonalAccessToken
+	if err := initializer.DB.Where("id = ? AND user_id = ?", c.Param("tokenId"), userModel.ID).First(&pat).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	if pat.RevokedAt == nil {
+		if err := initializer.DB.Model(&pat).Update("revoked_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
@@ -1,28 +1,32 @@
 package controller
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"os"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/jinzhu/gorm"
+	"github.com/khunaungpaing/the-blog-api/auth"
 	"github.com/khunaungpaing/the-blog-api/dto"
 	"github.com/khunaungpaing/the-blog-api/initializer"
 	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // SignUp godoc
 // @Summary Sign up a new user
-// @Description Create a new user
+// @Description Create a new user. When REGISTRATION_MODE is invite_only an invitation_code is required; when it is closed nobody can sign up.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param body body models.User true "User information"
 // @Success 201 {object} models.User
-// @Failure 400 {object} string
+// @Failure 400 {object} string "Invalid request, reserved username or password violates the password policy"
+// @Failure 403 {object} string
 // @Router /signup [post]
 func SignUp(c *gin.Context) {
 	// Get the email/password from the request body
@@ -35,6 +39,34 @@ func SignUp(c *gin.Context) {
 		return
 	}
 
+	// Check whether the user may sign up at all
+	mode := auth.RegistrationMode()
+	if mode == auth.RegistrationClosed {
+		c.JSON(http.StatusForbidden, gin.H{
+			"message": "Registration is closed",
+		})
+		return
+	}
+	if mode == auth.RegistrationInviteOnly && body.InvitationCode == "" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"message": "An invitation code is required",
+		})
+		return
+	}
+
+	if auth.IsReservedUsername(body.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "This username is reserved",
+		})
+		return
+	}
+
+	// Check the password against the password policy
+	if err := auth.ValidatePassword(body.Password, body.Username, body.Email); err != nil {
+		passwordPolicyError(c, err)
+		return
+	}
+
 	// Hash the password
 	hash, err := bcrypt.GenerateFromPassword([]byte(body.Password), 10)
 
@@ -45,21 +77,53 @@ func SignUp(c *gin.Context) {
 		return
 	}
 
+	// Look up the role given to new users
+	var role models.Role
+	if err := initializer.DB.First(&role, "name = ?", policy.DefaultRole).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to assign role",
+		})
+		return
+	}
+
 	// Create a new user
 	user := models.User{
 		Username: body.Username,
 		Email:    body.Email,
 		Password: string(hash),
+		RoleID:   role.ID,
 	}
 
-	// Save the user
-	result := initializer.DB.Create(&user)
-	if result.Error != nil {
+	// Save the user, consuming the invitation code in the same transaction
+	err = initializer.DB.Transaction(func(tx *gorm.DB) error {
+		if body.InvitationCode != "" {
+			invitation, err := auth.ConsumeInvitation(tx, body.InvitationCode, body.Email)
+			if err != nil {
+				return err
+			}
+			if invitation.RoleID != nil {
+				user.RoleID = *invitation.RoleID
+			}
+		}
+		return tx.Create(&user).Error
+	})
+	if errors.Is(err, auth.ErrInvalidInvitation) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"message": "Invalid or expired invitation code",
+		})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"message": result.Error.Error(),
+			"message": err.Error(),
 		})
 		return
 	}
+	// Ask the user to verify their email address
+	if err := sendVerificationEmail(user, user.Email); err != nil {
+		log.Printf("Failed to create verification email: %v", err)
+	}
+
 	user.Password = ""
 	// Return the user
 	c.JSON(http.StatusCreated, user)
@@ -68,13 +132,14 @@ func SignUp(c *gin.Context) {
 
 // Login godoc
 // @Summary Login an existing user
-// @Description Login an existing user
+// @Description Login an existing user. If the user has two-factor authentication enabled, the response contains an mfa_token that has to be exchanged at /users/login/2fa.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param body body models.LoginRequest true "User login information"
-// @Success 200 {object} models.User
+// @Success 200 {object} auth.TokenPair
 // @Failure 401 {object} string
+// @Failure 429 {object} string
 // @Router /login [post]
 func Login(c *gin.Context) {
 	// Get the email/password from the request body
@@ -86,37 +151,88 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	event := models.AuthEvent{
+		Email:     body.Email,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	// Refuse IPs with too many recent failures
+	if throttled, retryAfter := auth.IPThrottled(event.IP); throttled {
+		event.Event = models.AuthEventLoginBlocked
+		event.Reason = "ip_throttled"
+		auth.RecordAuthEvent(event)
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"message": "Too many failed login attempts, try again later",
+		})
+		return
+	}
+
 	// look up the requested user
 	var user models.User
-	if err := initializer.DB.First(&user, "email =?", body.Email).Error; err != nil {
-		if gorm.IsRecordNotFoundError(err) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"message": "User not found",
+	if err := initializer.DB.First(&user, "email = ?", body.Email).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "Failed to retrieve user",
 			})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": "Failed to retrieve user",
-		})
+		// Spend the same time as a password check so unknown emails cannot be told apart
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(body.Password))
+		event.Event = models.AuthEventLoginFailure
+		event.Reason = "unknown_email"
+		auth.RecordAuthEvent(event)
+		invalidCredentials(c)
+		return
+	}
+	event.UserID = &user.ID
+
+	// Locked accounts get the same response as a wrong password
+	if auth.IsLocked(user) {
+		event.Event = models.AuthEventLoginBlocked
+		event.Reason = "account_locked"
+		auth.RecordAuthEvent(event)
+		invalidCredentials(c)
 		return
 	}
 
 	// Check the password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"message": "Invalid password",
-		})
+		event.Event = models.AuthEventLoginFailure
+		event.Reason = "invalid_password"
+		auth.RecordAuthEvent(event)
+		if auth.RegisterLoginFailure(user) {
+			event.Event = models.AuthEventAccountLocked
+			event.Reason = ""
+			auth.RecordAuthEvent(event)
+		}
+		invalidCredentials(c)
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": user.Email,
-		"exp": time.Now().Add(time.Hour * 24 * 30).Unix(),
-	})
+	// Users with two-factor authentication have to enter a code first
+	if user.TOTPEnabled {
+		mfaToken, err := auth.GenerateMFAPendingToken(user)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"message": "Cannot create token",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
+		return
+	}
 
-	// Sign and get the complete encoded token as a string using the secret
-	tokenString, err := token.SignedString([]byte(os.Getenv("SECRET_KEY")))
+	auth.ResetLoginFailures(user)
+	event.Event = models.AuthEventLoginSuccess
+	auth.RecordAuthEvent(event)
 
+	// Start a new session and issue its access and refresh tokens
+	tokens, err := auth.StartSession(user, false, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"message": "Cannot create token",
@@ -124,9 +240,17 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// send the token
-	c.JSON(http.StatusOK, gin.H{
-		"token": tokenString,
+	// send the tokens
+	c.JSON(http.StatusOK, tokens)
+}
+
+// dummyPasswordHash is compared against when the email is unknown.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), 10)
+
+// invalidCredentials sends the uniform response for every failed login.
+func invalidCredentials(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"message": "Invalid email or password",
 	})
 }
 
@@ -160,8 +284,10 @@ func GetUserProfile(c *gin.Context) {
 }
 
 // UpdateUserProfile updates the profile of the logged-in user.
+// A changed email address is stored as pending until the new address is verified.
+// Changing the password requires the current one and revokes all other sessions and personal access tokens.
 // @Summary Update user profile
-// @Description Updates the profile of the logged-in user.
+// @Description Updates the profile of the logged-in user. Changing the password requires current_password and signs out all other sessions and personal access tokens.
 // @Tags Profile
 // @Security BearerAuth
 // @Accept json
@@ -170,7 +296,7 @@ func GetUserProfile(c *gin.Context) {
 // @Param body body dto.RequestUser true "User object"
 // @Success 200 {object} models.User "Updated user profile"
 // @Failure 400 {object} gin.H "Invalid request"
-// @Failure 401 {object} gin.H "User not found in context"
+// @Failure 401 {object} gin.H "User not found in context, or wrong current password"
 // @Failure 500 {object} gin.H "Failed to get user from context"
 // @Router /users/profile [patch]
 func UpdateUserProfile(c *gin.Context) {
@@ -192,14 +318,31 @@ func UpdateUserProfile(c *gin.Context) {
 		return
 	}
 
+	if body.Username != userModel.Username && auth.IsReservedUsername(body.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "This username is reserved"})
+		return
+	}
+
 	// Update user profile fields
 	userModel.Username = body.Username
-	userModel.Email = body.Email
 	userModel.Bio = body.Bio
 	userModel.ProfilePic = body.ProfilePic
 
-	// Hash the password if provided
-	if body.Password != "" {
+	// Hash the password if provided; changing it needs the current one
+	passwordChanged := body.Password != ""
+	if passwordChanged {
+		if err := bcrypt.CompareHashAndPassword([]byte(userModel.Password), []byte(body.CurrentPassword)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "The current password is required to change the password"})
+			return
+		}
+		email := userModel.Email
+		if body.Email != "" {
+			email = body.Email
+		}
+		if err := auth.ValidatePassword(body.Password, body.Username, email); err != nil {
+			passwordPolicyError(c, err)
+			return
+		}
 		hash, err := bcrypt.GenerateFromPassword([]byte(body.Password), 10)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"message": "Failed to hash password"})
@@ -208,13 +351,131 @@ func UpdateUserProfile(c *gin.Context) {
 		userModel.Password = string(hash)
 	}
 
+	// A new email address only takes effect once it has been verified
+	emailChanged := body.Email != "" && body.Email != userModel.Email
+	if emailChanged {
+		userModel.PendingEmail = body.Email
+	}
+
 	// Save the updated user profile
-	result := initializer.DB.Save(&userModel)
+	result := initializer.DB.Omit(clause.Associations).Save(&userModel)
 	if result.Error != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": result.Error.Error()})
 		return
 	}
 
+	// Sign out everywhere else, including personal access tokens, so that a
+	// stolen token cannot outlive a password change
+	if passwordChanged {
+		if err := auth.RevokeOtherSessions(userModel.ID, c.GetUint("session_id")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+			return
+		}
+		if err := auth.RevokeAllPersonalAccessTokens(userModel.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke personal access tokens"})
+			return
+		}
+	}
+
+	if emailChanged {
+		if err := sendVerificationEmail(userModel, userModel.PendingEmail); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to send verification email"})
+			return
+		}
+	}
+
 	userModel.Password = "" // Ensure password is not sent in response
 	c.JSON(http.StatusOK, userModel)
 }
+
+// passwordPolicyError responds with the rules a rejected password breaks.
+func passwordPolicyError(c *gin.Context, err error) {
+	var policyErr *auth.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check password"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":      "Password does not meet the password policy",
+		"violations": policyErr.Violations,
+	})
+}
+
+// GetPublicProfile returns the public profile of an author and their published posts.
+// @Summary Get an author's public profile
+// @Description Returns the public profile of the user with the given username, with post, like and comment counts and a paginated list of their published posts.
+// @Tags Profile
+// @Produce json
+// @Param username path string true "Username"
+// @Param page query int false "Page number (default: 1)"
+// @Param pageSize query int false "Number of items per page (default: 10)"
+// @Success 200 {object} gin.H "Public profile and posts"
+// @Failure 404 {object} gin.H "User not found"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/{username} [get]
+func GetPublicProfile(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	var user models.User
+	if err := initializer.DB.First(&user, "username = ?", c.Param("username")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	profile := dto.PublicProfile{
+		ID:         user.ID,
+		Username:   user.Username,
+		Bio:        user.Bio,
+		ProfilePic: user.ProfilePic,
+		JoinedAt:   user.CreatedAt,
+	}
+
+	publishedPosts := initializer.DB.Model(&models.Post{}).Where("user_id = ? AND status = ?", user.ID, models.PostStatusPublished)
+	if err := publishedPosts.Count(&profile.PostCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count posts"})
+		return
+	}
+	if err := initializer.DB.Model(&models.Like{}).
+		Where("post_id IN (?)", initializer.DB.Model(&models.Post{}).Select("id").Where("user_id = ? AND status = ?", user.ID, models.PostStatusPublished)).
+		Count(&profile.LikeCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count likes"})
+		return
+	}
+	if err := initializer.DB.Model(&models.Comment{}).Where("user_id = ?", user.ID).Count(&profile.CommentCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count comments"})
+		return
+	}
+	if err := initializer.DB.Model(&models.Follow{}).Where("followee_id = ?", user.ID).Count(&profile.FollowerCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count followers"})
+		return
+	}
+	if err := initializer.DB.Model(&models.Follow{}).Where("follower_id = ?", user.ID).Count(&profile.FollowingCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count followed users"})
+		return
+	}
+
+	var posts []models.Post
+	if err := initializer.DB.Preload("Categories").Preload("Tags").Preload("Media").
+		Where("user_id = ? AND status = ?", user.ID, models.PostStatusPublished).
+		Order(postDate + " DESC, posts.id DESC").Offset(offset).Limit(pageSize).
+		Find(&posts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile":     profile,
+		"posts":       posts,
+		"currentPage": page,
+		"pageSize":    pageSize,
+		"totalCount":  profile.PostCount,
+	})
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/auth"
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/mailer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm/clause"
+)
+
+// VerifyEmail verifies the email address of a user with the token from the verification link.
+// If the token was issued for a pending email change, the new address replaces the old one.
+// @Summary Verify an email address
+// @Description Verifies the email address using the signed token from the verification link.
+// @Tags users
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} gin.H "Email verified successfully"
+// @Failure 400 {object} gin.H "Invalid or expired token"
+// @Failure 409 {object} gin.H "Email address already in use"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/verify-email [get]
+func VerifyEmail(c *gin.Context) {
+	userID, email, err := auth.ParseEmailVerificationToken(c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired verification token"})
+		return
+	}
+
+	var user models.User
+	if err := initializer.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired verification token"})
+		return
+	}
+
+	switch email {
+	case user.PendingEmail:
+		var count int64
+		initializer.DB.Model(&models.User{}).Where("email = ? AND id <> ?", email, user.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"message": "Email address already in use"})
+			return
+		}
+		user.Email = user.PendingEmail
+		user.PendingEmail = ""
+	case user.Email:
+		if user.IsVerified() {
+			c.JSON(http.StatusOK, gin.H{"message": "Email already verified"})
+			return
+		}
+	default:
+		// The token was issued for an address the user no longer uses
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid or expired verification token"})
+		return
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := initializer.DB.Omit(clause.Associations).Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerificationEmail sends the verification link again.
+// If an email change is pending, the link is sent to the new address.
+// @Summary Resend the verification email
+// @Description Sends a new verification link to the unverified or pending email address of the logged-in user.
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Authorization token using the Bearer scheme"
+// @Success 200 {object} gin.H "Verification email sent"
+// @Failure 400 {object} gin.H "Email already verified"
+// @Failure 401 {object} gin.H "Unauthorized access, missing or invalid token"
+// @Failure 500 {object} gin.H "Internal server error"
+// @Router /users/verify-email/resend [post]
+func ResendVerificationEmail(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found in context"})
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from context"})
+		return
+	}
+
+	email := userModel.PendingEmail
+	if email == "" {
+		if userModel.IsVerified() {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Email already verified"})
+			return
+		}
+		email = userModel.Email
+	}
+
+	if err := sendVerificationEmail(userModel, email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// sendVerificationEmail signs a verification link for the address and mails it in the background.
+func sendVerificationEmail(user models.User, email string) error {
+	token, err := auth.GenerateEmailVerificationToken(user, email)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/v1/users/verify-email?token=%s", os.Getenv("APP_URL"), url.QueryEscape(token))
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.Username, auth.EmailVerificationTTL(), link),
+	}
+	go func() {
+		if err := initializer.Mailer.Send(msg); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+		}
+	}()
+	return nil
+}
@@ -1,13 +1,19 @@
 package dto
 
-import "github.com/khunaungpaing/the-blog-api/models"
+import (
+	"time"
+
+	"github.com/khunaungpaing/the-blog-api/models"
+)
 
 type RequestPost struct {
 	ID         uint              `json:"id"`
 	Title      string            `json:"title"`
 	Content    string            `json:"content"`
+	Format     string            `json:"content_format"` // markdown, html (default) or plain
 	Slug       string            `json:"slug"`
 	Status     string            `json:"status"`
+	PublishAt  *time.Time        `json:"publish_at"` // Required for scheduled posts
 	Categories []RequestCategory `json:"categories"`
 	Tags       []RequestTag      `json:"tags"`
 	Media      RequestMedia      `json:"media"`
@@ -17,10 +23,9 @@ func (rp RequestPost) ToModel(post models.Post) models.Post {
 	post.ID = rp.ID
 	post.Title = rp.Title
 	post.Content = rp.Content
+	post.ContentFormat = rp.Format
 	post.Slug = rp.Slug
 	post.Status = rp.Status
-	post.Categories = RequestCategoryToModelList(rp.Categories)
-	post.Tags = RequestTagToModelList(rp.Tags)
 	if post.Media == nil {
 		post.Media = new(models.Media)
 	}
@@ -28,30 +33,20 @@ func (rp RequestPost) ToModel(post models.Post) models.Post {
 	return post
 }
 
+// RequestCategory attaches a category to a post by ID, or by name, in which
+// case a category that does not exist yet is created.
 type RequestCategory struct {
 	ID          uint   `json:"id"`
 	Name        string `json:"name"`
-	Description string `json:"description"`
-}
-
-func (rc RequestCategory) ToModel(category models.Category) models.Category {
-	category.ID = rc.ID
-	category.Name = rc.Name
-	category.Description = rc.Description
-	return category
+	Description string `json:"description"` // Only used when the category is created
 }
 
+// RequestTag attaches a tag to a post by ID, or by name, in which case a tag
+// that does not exist yet is created.
 type RequestTag struct {
 	ID          uint   `json:"id"`
 	Name        string `json:"name"`
-	Description string `json:"description"`
-}
-
-func (rt RequestTag) ToModel(tag models.Tag) models.Tag {
-	tag.ID = rt.ID
-	tag.Name = rt.Name
-	tag.Description = rt.Description
-	return tag
+	Description string `json:"description"` // Only used when the tag is created
 }
 
 type RequestMedia struct {
@@ -72,20 +67,16 @@ type RequestComment struct {
 	Content string `json:"content"`
 }
 
-func RequestCategoryToModelList(list []RequestCategory) []models.Category {
-	var categories []models.Category
-	for _, rc := range list {
-		categories = append(categories, rc.ToModel(models.Category{}))
-	}
-	return categories
+// RequestTerm creates or updates a category or tag. On update, an empty name
+// and a missing description are left unchanged.
+type RequestTerm struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
 }
 
-func RequestTagToModelList(list []RequestTag) []models.Tag {
-	var tags []models.Tag
-	for _, rt := range list {
-		tags = append(tags, rt.ToModel(models.Tag{}))
-	}
-	return tags
+// RequestMerge lists the categories or tags to merge into another one.
+type RequestMerge struct {
+	SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
 }
 
 type RequestUser struct {
@@ -95,4 +86,6 @@ type RequestUser struct {
 	Bio        string `json:"bio"`
 	ProfilePic string `json:"profile_pic"`
 	Password   string `json:"password"`
+
+	CurrentPassword string `json:"current_password"` // Required to change the password
 }
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/textdiff"
+)
+
+// PublicProfile is the part of a user's profile that anyone can see.
+type PublicProfile struct {
+	ID             uint      `json:"id"`
+	Username       string    `json:"username"`
+	Bio            string    `json:"bio"`
+	ProfilePic     string    `json:"profile_pic"`
+	JoinedAt       time.Time `json:"joined_at"`
+	PostCount      int64     `json:"post_count"`      // Published posts
+	LikeCount      int64     `json:"like_count"`      // Likes received on published posts
+	CommentCount   int64     `json:"comment_count"`   // Comments written
+	FollowerCount  int64     `json:"follower_count"`  // Users following the author
+	FollowingCount int64     `json:"following_count"` // Authors the user follows
+}
+
+// PublicUser is a user in lists such as followers, without any private fields.
+type PublicUser struct {
+	ID         uint   `json:"id"`
+	Username   string `json:"username"`
+	Bio        string `json:"bio"`
+	ProfilePic string `json:"profile_pic"`
+}
+
+// DataExport holds everything the API stores about a user.
+type DataExport struct {
+	ExportedAt time.Time        `json:"exported_at"`
+	Profile    models.User      `json:"profile"`
+	Posts      []models.Post    `json:"posts"`    // With categories, tags and media metadata
+	Comments   []models.Comment `json:"comments"` // Comments written by the user
+	Likes      []models.Like    `json:"likes"`    // Likes given by the user
+
+	Revisions []models.PostRevision `json:"revisions"` // Revisions of the user's posts and revisions the user made
+}
+
+// RevisionDiff is the difference between two revisions of a post.
+type RevisionDiff struct {
+	From       int              `json:"from"`
+	To         int              `json:"to"`
+	Mode       string           `json:"mode"` // "line" or "word"
+	Title      []textdiff.Chunk `json:"title"`
+	Content    []textdiff.Chunk `json:"content"`
+	Categories TermChanges      `json:"categories"`
+	Tags       TermChanges      `json:"tags"`
+}
+
+// TermChanges lists the categories or tags one revision added and removed.
+type TermChanges struct {
+	Added   []models.RevisionTerm `json:"added"`
+	Removed []models.RevisionTerm `json:"removed"`
+}
+
+// PostSearchResult is a post found by a full-text search.
+type PostSearchResult struct {
+	Post           models.Post `json:"post"`
+	Rank           float64     `json:"rank"`
+	TitleHighlight string      `json:"title_highlight"` // HTML-escaped title with matches in <mark>
+	Snippet        string      `json:"snippet"`         // HTML-escaped content fragments with matches in <mark>
+}
+
+// Term is a category or tag with the number of published posts filed under it.
+type Term struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	PostCount   int64     `json:"post_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
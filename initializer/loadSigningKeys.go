@@ -0,0 +1,173 @@
+package initializer
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SigningKey is an RSA key used to sign or verify JWTs, identified by its kid.
+// Retired keys only have a public key and are kept to verify tokens issued
+// before a rotation.
+type SigningKey struct {
+	ID         string
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+}
+
+var (
+	// ActiveSigningKey signs new tokens.
+	ActiveSigningKey *SigningKey
+	// VerificationKeys are all keys tokens are accepted from, by kid.
+	VerificationKeys map[string]*SigningKey
+)
+
+// LoadSigningKeys loads the JWT keys from JWT_KEYS_DIR (default "keys").
+// Every <kid>.pem file holds a PKCS#8 or PKCS#1 private key and every
+// <kid>.pub.pem file a PKIX public key of a retired key. JWT_SIGNING_KID
+// selects the key used for signing; when it is empty the newest private key
+// is used. If the directory has no keys, a new one is generated so the API
+// can run out of the box in development.
+func LoadSigningKeys() {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		dir = "keys"
+	}
+
+	keys, err := readSigningKeys(dir)
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+
+	if len(keys) == 0 {
+		key, err := generateSigningKey(dir)
+		if err != nil {
+			log.Fatalf("Failed to generate signing key: %v", err)
+		}
+		log.Printf("Generated new signing key %s in %s", key.ID, dir)
+		keys = []*SigningKey{key}
+	}
+
+	VerificationKeys = make(map[string]*SigningKey, len(keys))
+	for _, key := range keys {
+		VerificationKeys[key.ID] = key
+	}
+
+	kid := os.Getenv("JWT_SIGNING_KID")
+	if kid == "" {
+		// Key IDs are generated from the creation time, so the last one is the newest
+		for _, key := range keys {
+			if key.PrivateKey != nil {
+				kid = key.ID
+			}
+		}
+	}
+	ActiveSigningKey = VerificationKeys[kid]
+	if ActiveSigningKey == nil || ActiveSigningKey.PrivateKey == nil {
+		log.Fatalf("No private key found for signing key %q", kid)
+	}
+}
+
+func readSigningKeys(dir string) ([]*SigningKey, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*SigningKey
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".pem") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM data", name)
+		}
+
+		key := &SigningKey{}
+		if kid, ok := strings.CutSuffix(name, ".pub.pem"); ok {
+			key.ID = kid
+			key.PublicKey, err = parsePublicKey(block.Bytes)
+		} else {
+			key.ID = strings.TrimSuffix(name, ".pem")
+			key.PrivateKey, err = parsePrivateKey(block.Bytes)
+			if key.PrivateKey != nil {
+				key.PublicKey = &key.PrivateKey.PublicKey
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func parsePrivateKey(der []byte) (*rsa.PrivateKey, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return key, nil
+}
+
+func parsePublicKey(der []byte) (*rsa.PublicKey, error) {
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+	return key, nil
+}
+
+func generateSigningKey(dir string) (*SigningKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	kid := time.Now().UTC().Format("20060102150405")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{ID: kid, PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}, nil
+}
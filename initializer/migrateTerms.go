@@ -0,0 +1,60 @@
+package initializer
+
+import (
+	"log"
+
+	"github.com/khunaungpaing/the-blog-api/taxonomy"
+	"gorm.io/gorm"
+)
+
+// migrateTerms fills in the normalized names of categories or tags created
+// before names had to be unique, and merges the duplicates into the oldest
+// one so that the unique index can be created. It only runs once, before
+// the normalized_name column exists.
+func migrateTerms(kind taxonomy.Kind) {
+	if !DB.Migrator().HasTable(kind.New()) || DB.Migrator().HasColumn(kind.New(), "NormalizedName") {
+		return
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(kind.New(), "NormalizedName"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(kind.New(), "MergedIntoID"); err != nil {
+			return err
+		}
+		var terms []struct {
+			ID   uint
+			Name string
+		}
+		if err := tx.Model(kind.New()).Select("id", "name").Order("id").Find(&terms).Error; err != nil {
+			return err
+		}
+
+		kept := map[string]uint{}
+		duplicates := map[uint][]uint{}
+		for _, term := range terms {
+			normalized := taxonomy.Normalize(term.Name)
+			if keptID, ok := kept[normalized]; ok {
+				duplicates[keptID] = append(duplicates[keptID], term.ID)
+				continue
+			}
+			kept[normalized] = term.ID
+			if err := tx.Model(kind.New()).Where("id = ?", term.ID).UpdateColumns(map[string]interface{}{
+				"name":            taxonomy.CleanName(term.Name),
+				"normalized_name": normalized,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		for keptID, ids := range duplicates {
+			if err := taxonomy.Merge(tx, kind, keptID, ids); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to merge duplicate %s: %v", kind.Table, err)
+	}
+}
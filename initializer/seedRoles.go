@@ -0,0 +1,63 @@
+package initializer
+
+import (
+	"log"
+	"os"
+
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
+)
+
+// SeedRoles makes sure the default roles and permissions exist, assigns the
+// default role to users without one and promotes ADMIN_EMAIL to admin.
+func SeedRoles() {
+	newPermissions := map[string]bool{}
+	for name, description := range policy.Permissions {
+		permission := models.Permission{Name: name}
+		result := DB.Where(models.Permission{Name: name}).Attrs(models.Permission{Description: description}).FirstOrCreate(&permission)
+		if result.Error != nil {
+			log.Fatalf("Failed to seed permission %s: %v", name, result.Error)
+		}
+		if result.RowsAffected > 0 {
+			newPermissions[name] = true
+		}
+	}
+
+	for name, permissionNames := range policy.DefaultRoles {
+		var role models.Role
+		result := DB.Where(models.Role{Name: name}).FirstOrCreate(&role)
+		if result.Error != nil {
+			log.Fatalf("Failed to seed role %s: %v", name, result.Error)
+		}
+		// Only grant the default permissions to newly created roles so that
+		// changes made to existing roles in the database are preserved.
+		// Existing roles only receive permissions added since the last start.
+		grant := permissionNames
+		if result.RowsAffected == 0 {
+			grant = nil
+			for _, permissionName := range permissionNames {
+				if newPermissions[permissionName] {
+					grant = append(grant, permissionName)
+				}
+			}
+		}
+		if len(grant) == 0 {
+			continue
+		}
+		var permissions []models.Permission
+		DB.Where("name IN ?", grant).Find(&permissions)
+		if err := DB.Model(&role).Association("Permissions").Append(permissions); err != nil {
+			log.Fatalf("Failed to seed permissions of role %s: %v", name, err)
+		}
+	}
+
+	var defaultRole models.Role
+	DB.First(&defaultRole, "name = ?", policy.DefaultRole)
+	DB.Model(&models.User{}).Where("role_id IS NULL OR role_id = 0").Update("role_id", defaultRole.ID)
+
+	if adminEmail := os.Getenv("ADMIN_EMAIL"); adminEmail != "" {
+		var adminRole models.Role
+		DB.First(&adminRole, "name = ?", policy.RoleAdmin)
+		DB.Model(&models.User{}).Where("email = ?", adminEmail).Update("role_id", adminRole.ID)
+	}
+}
@@ -0,0 +1,32 @@
+package initializer
+
+import (
+	"os"
+
+	"github.com/khunaungpaing/the-blog-api/mailer"
+)
+
+var Mailer mailer.Mailer
+
+// SetupMailer configures the mail transport selected by MAIL_TRANSPORT.
+// "smtp" sends through MAIL_HOST/MAIL_PORT, anything else keeps the messages
+// in an outbox that is written to MAIL_OUTBOX_DIR when set.
+func SetupMailer() {
+	from := os.Getenv("MAIL_FROM")
+
+	switch os.Getenv("MAIL_TRANSPORT") {
+	case "smtp":
+		Mailer = &mailer.SMTPMailer{
+			Host:     os.Getenv("MAIL_HOST"),
+			Port:     os.Getenv("MAIL_PORT"),
+			Username: os.Getenv("MAIL_USERNAME"),
+			Password: os.Getenv("MAIL_PASSWORD"),
+			From:     from,
+		}
+	default:
+		Mailer = &mailer.Outbox{
+			Dir:  os.Getenv("MAIL_OUTBOX_DIR"),
+			From: from,
+		}
+	}
+}
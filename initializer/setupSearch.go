@@ -0,0 +1,85 @@
+package initializer
+
+import "log"
+
+// searchStatements keep posts.search_vector up to date. Titles weigh the most
+// (A), then tag and category names (B), then the content (C). Triggers on
+// posts recompute the vector when the title or content changes, and triggers
+// on the join tables and on tags and categories recompute it for the posts
+// whose tags or categories change. The 'english' configuration has to match
+// the one the search queries use.
+var searchStatements = []string{
+	`CREATE OR REPLACE FUNCTION post_search_document(p_id bigint, p_title text, p_content text) RETURNS tsvector AS $$
+		SELECT setweight(to_tsvector('english', coalesce(p_title, '')), 'A')
+			|| setweight(to_tsvector('english',
+				coalesce((SELECT string_agg(tags.name, ' ') FROM tags JOIN post_tags ON post_tags.tag_id = tags.id
+					WHERE post_tags.post_id = p_id AND tags.deleted_at IS NULL), '') || ' ' ||
+				coalesce((SELECT string_agg(categories.name, ' ') FROM categories JOIN post_categories ON post_categories.category_id = categories.id
+					WHERE post_categories.post_id = p_id AND categories.deleted_at IS NULL), '')), 'B')
+			|| setweight(to_tsvector('english', coalesce(p_content, '')), 'C')
+	$$ LANGUAGE sql STABLE`,
+
+	`CREATE OR REPLACE FUNCTION posts_search_vector_trigger() RETURNS trigger AS $$
+	BEGIN
+		NEW.search_vector := post_search_document(NEW.id, NEW.title, NEW.content);
+		RETURN NEW;
+	END
+	$$ LANGUAGE plpgsql`,
+
+	`CREATE OR REPLACE FUNCTION post_terms_search_vector_trigger() RETURNS trigger AS $$
+	DECLARE
+		affected bigint;
+	BEGIN
+		IF TG_OP = 'DELETE' THEN
+			affected := OLD.post_id;
+		ELSE
+			affected := NEW.post_id;
+		END IF;
+		UPDATE posts SET search_vector = post_search_document(id, title, content) WHERE id = affected;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`,
+
+	`CREATE OR REPLACE FUNCTION terms_search_vector_trigger() RETURNS trigger AS $$
+	BEGIN
+		IF TG_TABLE_NAME = 'tags' THEN
+			UPDATE posts SET search_vector = post_search_document(id, title, content)
+				WHERE id IN (SELECT post_id FROM post_tags WHERE tag_id = NEW.id);
+		ELSE
+			UPDATE posts SET search_vector = post_search_document(id, title, content)
+				WHERE id IN (SELECT post_id FROM post_categories WHERE category_id = NEW.id);
+		END IF;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`,
+
+	`DROP TRIGGER IF EXISTS posts_search_vector ON posts`,
+	`CREATE TRIGGER posts_search_vector BEFORE INSERT OR UPDATE OF title, content ON posts
+		FOR EACH ROW EXECUTE FUNCTION posts_search_vector_trigger()`,
+	`DROP TRIGGER IF EXISTS post_tags_search_vector ON post_tags`,
+	`CREATE TRIGGER post_tags_search_vector AFTER INSERT OR DELETE ON post_tags
+		FOR EACH ROW EXECUTE FUNCTION post_terms_search_vector_trigger()`,
+	`DROP TRIGGER IF EXISTS post_categories_search_vector ON post_categories`,
+	`CREATE TRIGGER post_categories_search_vector AFTER INSERT OR DELETE ON post_categories
+		FOR EACH ROW EXECUTE FUNCTION post_terms_search_vector_trigger()`,
+	`DROP TRIGGER IF EXISTS tags_search_vector ON tags`,
+	`CREATE TRIGGER tags_search_vector AFTER UPDATE OF name, deleted_at ON tags
+		FOR EACH ROW EXECUTE FUNCTION terms_search_vector_trigger()`,
+	`DROP TRIGGER IF EXISTS categories_search_vector ON categories`,
+	`CREATE TRIGGER categories_search_vector AFTER UPDATE OF name, deleted_at ON categories
+		FOR EACH ROW EXECUTE FUNCTION terms_search_vector_trigger()`,
+
+	// Posts written before search existed
+	`UPDATE posts SET search_vector = post_search_document(id, title, content) WHERE search_vector IS NULL`,
+}
+
+// setupPostSearch installs the full-text search triggers. It runs after the
+// tables have been migrated.
+func setupPostSearch() {
+	for _, statement := range searchStatements {
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Printf("Failed to set up post search: %v", err)
+			return
+		}
+	}
+}
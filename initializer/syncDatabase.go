@@ -1,8 +1,25 @@
 package initializer
 
-import "github.com/khunaungpaing/the-blog-api/models"
+import (
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/render"
+	"github.com/khunaungpaing/the-blog-api/taxonomy"
+	"gorm.io/gorm"
+)
 
 func SyncDataBase() {
+	// The status check was replaced when the scheduled status was added
+	if DB.Migrator().HasConstraint(&models.Post{}, "chk_posts_status") {
+		DB.Migrator().DropConstraint(&models.Post{}, "chk_posts_status")
+	}
+
+	// Users who signed up before email verification existed count as verified
+	verifyExistingUsers := DB.Migrator().HasTable(&models.User{}) && !DB.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+
+	// Category and tag names became unique
+	migrateTerms(taxonomy.Categories)
+	migrateTerms(taxonomy.Tags)
+
 	DB.AutoMigrate(
 		&models.User{},
 		&models.Post{},
@@ -11,5 +28,49 @@ func SyncDataBase() {
 		&models.Tag{},
 		&models.Media{},
 		&models.Category{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.Role{},
+		&models.Permission{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.PersonalAccessToken{},
+		&models.AuthEvent{},
+		&models.Follow{},
+		&models.Block{},
+		&models.Mute{},
+		&models.Invitation{},
+		&models.AuditLog{},
+		&models.PostSlug{},
+		&models.PostRevision{},
 	)
+	setupPostSearch()
+
+	if verifyExistingUsers {
+		DB.Model(&models.User{}).
+			Where("email_verified_at IS NULL").
+			Update("email_verified_at", gorm.Expr("created_at"))
+	}
+
+	// Posts published before published_at existed count as published when created
+	DB.Model(&models.Post{}).
+		Where("status = ? AND published_at IS NULL", models.PostStatusPublished).
+		Update("published_at", gorm.Expr("created_at"))
+
+	// Posts written before content_html existed are rendered once
+	var posts []models.Post
+	DB.Select("id", "content", "content_format").
+		Where("content_html = '' AND content <> ''").
+		FindInBatches(&posts, 100, func(tx *gorm.DB, batch int) error {
+			for _, post := range posts {
+				rendered, err := render.HTML(post.ContentFormat, post.Content)
+				if err != nil {
+					return err
+				}
+				if err := DB.Model(&post).UpdateColumn("content_html", rendered).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
 }
@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outbox keeps sent messages in memory instead of delivering them. If Dir is
+// set, every message is also written to that directory as an .eml file.
+type Outbox struct {
+	Dir  string
+	From string
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Send records the message in the outbox.
+func (o *Outbox) Send(msg Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.messages = append(o.messages, msg)
+
+	if o.Dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(o.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%d.eml", time.Now().UnixNano(), len(o.messages))
+	return os.WriteFile(filepath.Join(o.Dir, name), formatMessage(o.From, msg), 0o644)
+}
+
+// Messages returns a copy of the messages sent so far.
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages := make([]Message, len(o.messages))
+	copy(messages, o.messages)
+	return messages
+}
@@ -1,19 +1,27 @@
 package main
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/auth"
 	"github.com/khunaungpaing/the-blog-api/controller"
 	"github.com/khunaungpaing/the-blog-api/docs"
 	"github.com/khunaungpaing/the-blog-api/initializer"
 	"github.com/khunaungpaing/the-blog-api/middleware"
+	"github.com/khunaungpaing/the-blog-api/policy"
+	"github.com/khunaungpaing/the-blog-api/scheduler"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 func init() {
 	initializer.LoadEnvVariable()
+	initializer.LoadSigningKeys()
 	initializer.ConnectToDB()
+	initializer.SetupMailer()
 	initializer.SyncDataBase()
+	initializer.SeedRoles()
 }
 
 // main is the entry point of the application
@@ -27,54 +35,213 @@ func init() {
 // @host localhost:8080
 // @BasePath /api/v1
 func main() {
+	// Purge the accounts whose deletion grace period is over
+	go auth.StartAccountPurger(time.Hour)
+
+	// Publish scheduled posts once they are due
+	go scheduler.StartPostPublisher(scheduler.PublishInterval())
+
 	r := gin.Default()
 
 	// Initialize the swagger documentation
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Publish the public keys used to verify tokens
+	r.GET("/.well-known/jwks.json", controller.GetJWKS)
+
 	// Initialize the version 1 of the API
 	v1 := r.Group("/api/v1")
 	{
 		v1.POST("/users/signup", controller.SignUp)
 		v1.POST("/users/login", controller.Login)
-		v1.GET("/users/profile", middleware.RequireAuth, controller.GetUserProfile)
-		v1.PATCH("/users", middleware.RequireAuth, controller.UpdateUserProfile)
+		v1.POST("/users/login/2fa", controller.LoginMFA)
+		v1.POST("/users/token/refresh", controller.RefreshToken)
+		v1.GET("/users/verify-email", controller.VerifyEmail)
+		v1.POST("/users/verify-email/resend", middleware.RequireAuth, middleware.RequireSession, controller.ResendVerificationEmail)
+		v1.POST("/users/password/forgot", controller.ForgotPassword)
+		v1.POST("/users/password/reset", controller.ResetPassword)
+		v1.POST("/users/logout", middleware.RequireAuth, middleware.RequireSession, controller.Logout)
+		v1.POST("/users/logout/all", middleware.RequireAuth, middleware.RequireSession, controller.LogoutAll)
+		v1.GET("/users/profile", middleware.RequireAuth, middleware.RequireScope(policy.ScopeProfileRead), controller.GetUserProfile)
+		v1.PATCH("/users", middleware.RequireAuth, middleware.RequireSession, controller.UpdateUserProfile)
+		v1.DELETE("/users", middleware.RequireAuth, middleware.RequireSession, controller.DeleteAccount)
+		v1.POST("/users/deletion/cancel", middleware.RequireAuth, middleware.RequireSession, controller.CancelAccountDeletion)
+		v1.GET("/users/export", middleware.RequireAuth, middleware.RequireSession, controller.ExportUserData)
+		v1.GET("/users/:username", controller.GetPublicProfile)
+	}
+
+	// Initialize the invitation endpoints
+	invitations := v1.Group("/invitations", middleware.RequireAuth, middleware.RequireSession, middleware.RequirePermission(policy.CreateInvitation))
+	{
+		// Issue an invitation code
+		invitations.POST("", controller.CreateInvitation)
+		// List invitation codes
+		invitations.GET("", controller.ListInvitations)
+		// Revoke an invitation code
+		invitations.DELETE("/:invitationId", controller.RevokeInvitation)
+	}
+
+	// Initialize the follow endpoints
+	follow := v1.Group("/users/:username")
+	{
+		// Follow a specific author
+		follow.POST("/follow", middleware.RequireAuth, middleware.RequireSession, controller.FollowUser)
+		// Unfollow a specific author
+		follow.DELETE("/follow", middleware.RequireAuth, middleware.RequireSession, controller.UnfollowUser)
+		// Get the followers of a specific user
+		follow.GET("/followers", controller.GetFollowers)
+		// Get the authors a specific user follows
+		follow.GET("/following", controller.GetFollowing)
+		// Block a specific user
+		follow.POST("/block", middleware.RequireAuth, middleware.RequireSession, controller.BlockUser)
+		// Unblock a specific user
+		follow.DELETE("/block", middleware.RequireAuth, middleware.RequireSession, controller.UnblockUser)
+		// Mute a specific user
+		follow.POST("/mute", middleware.RequireAuth, middleware.RequireSession, controller.MuteUser)
+		// Unmute a specific user
+		follow.DELETE("/mute", middleware.RequireAuth, middleware.RequireSession, controller.UnmuteUser)
+	}
+
+	// Get the users blocked and muted by the logged-in user
+	v1.GET("/users/blocks", middleware.RequireAuth, middleware.RequireSession, controller.ListBlockedUsers)
+	v1.GET("/users/mutes", middleware.RequireAuth, middleware.RequireSession, controller.ListMutedUsers)
+
+	// Get the published posts of followed authors
+	v1.GET("/feed", middleware.RequireAuth, middleware.RequireScope(policy.ScopePostsRead), controller.GetFeed)
+
+	// Initialize the two-factor authentication endpoints
+	mfa := v1.Group("/users/2fa", middleware.RequireAuth, middleware.RequireSession)
+	{
+		// Start enrolment and get the provisioning URI
+		mfa.POST("/enroll", controller.EnrollTOTP)
+		// Confirm enrolment with the first code
+		mfa.POST("/confirm", controller.ConfirmTOTP)
+		// Turn two-factor authentication off
+		mfa.POST("/disable", controller.DisableTOTP)
+		// Replace the recovery codes
+		mfa.POST("/recovery-codes", controller.RegenerateRecoveryCodes)
+	}
+
+	// Initialize the session endpoints
+	sessions := v1.Group("/users/sessions", middleware.RequireAuth, middleware.RequireSession)
+	{
+		// Get the devices the user is logged in on
+		sessions.GET("", controller.ListSessions)
+		// Log a specific device out
+		sessions.DELETE("/:sessionId", controller.RevokeUserSession)
+	}
+
+	// Initialize the personal access token endpoints
+	tokens := v1.Group("/users/tokens", middleware.RequireAuth, middleware.RequireSession)
+	{
+		// Create a new personal access token
+		tokens.POST("", controller.CreatePersonalAccessToken)
+		// Get all the personal access tokens
+		tokens.GET("", controller.ListPersonalAccessTokens)
+		// Revoke a specific personal access token
+		tokens.DELETE("/:tokenId", controller.RevokePersonalAccessToken)
 	}
 
 	// Initialize the posts endpoint
 	post := v1.Group("/posts")
 	{
 		// Create a new post
-		post.POST("/", middleware.RequireAuth, controller.CreatePost)
+		post.POST("/", middleware.RequireAuth, middleware.RequireScope(policy.ScopePostsWrite), middleware.RequireMFA, middleware.RequirePermission(policy.CreatePost), controller.CreatePost)
 		// Get all the posts
-		post.GET("/", controller.GetPosts)
+		post.GET("/", middleware.OptionalAuth, middleware.RequireScope(policy.ScopePostsRead), controller.GetPosts)
+		// Search the posts
+		post.GET("/search", middleware.OptionalAuth, middleware.RequireScope(policy.ScopePostsRead), controller.SearchPosts)
+		// Get a specific post by its slug
+		post.GET("/by-slug/:slug", middleware.OptionalAuth, middleware.RequireScope(policy.ScopePostsRead), controller.GetPostBySlug)
 		// Get a specific post
-		post.GET("/:postId", controller.GetPost)
+		post.GET("/:postId", middleware.OptionalAuth, middleware.RequireScope(policy.ScopePostsRead), controller.GetPost)
 		// Delete a specific post
-		post.DELETE("/:postId", middleware.RequireAuth, controller.DeletePost)
+		post.DELETE("/:postId", middleware.RequireAuth, middleware.RequireScope(policy.ScopePostsWrite), middleware.RequireMFA, controller.DeletePost)
 		// Update a specific post
-		post.PATCH("/:postId", middleware.RequireAuth, controller.UpdatePost)
+		post.PATCH("/:postId", middleware.RequireAuth, middleware.RequireScope(policy.ScopePostsWrite), middleware.RequireMFA, controller.UpdatePost)
 	}
 
 	// Initialize the comments endpoint for a specific post
 	postIdRoute := post.Group("/:postId")
 	{
 		// Create a new comment for a specific post
-		postIdRoute.POST("/comments", middleware.RequireAuth, controller.CreateComment)
+		postIdRoute.POST("/comments", middleware.RequireAuth, middleware.RequireScope(policy.ScopeCommentsWrite), middleware.RequireMFA, middleware.RequireVerifiedEmail, middleware.RequirePermission(policy.CreateComment), controller.CreateComment)
 		// Get all the comments for a specific post
-		postIdRoute.GET("/comments", middleware.RequireAuth, controller.GetCommentsForPost)
+		postIdRoute.GET("/comments", middleware.RequireAuth, middleware.RequireScope(policy.ScopeCommentsRead), controller.GetCommentsForPost)
 		// Delete a specific comment for a specific post
-		postIdRoute.DELETE("/comments/:commentId", middleware.RequireAuth, controller.DeleteComment)
+		postIdRoute.DELETE("/comments/:commentId", middleware.RequireAuth, middleware.RequireScope(policy.ScopeCommentsWrite), middleware.RequireMFA, controller.DeleteComment)
 		// Update a specific comment for a specific post
-		postIdRoute.PATCH("/comments/:commentId", middleware.RequireAuth, controller.UpdateComment)
+		postIdRoute.PATCH("/comments/:commentId", middleware.RequireAuth, middleware.RequireScope(policy.ScopeCommentsWrite), middleware.RequireMFA, controller.UpdateComment)
 
 		// Like the comments endpoint
-		postIdRoute.POST("/likes", middleware.RequireAuth, controller.LikePost)
+		postIdRoute.POST("/likes", middleware.RequireAuth, middleware.RequireScope(policy.ScopeLikesWrite), middleware.RequireMFA, controller.LikePost)
 		// Get all the likes for a specific post
-		postIdRoute.GET("/likes", middleware.RequireAuth, controller.GetLikesForPost)
+		postIdRoute.GET("/likes", middleware.RequireAuth, middleware.RequireScope(policy.ScopeLikesRead), controller.GetLikesForPost)
 		// Unlike a specific post
-		postIdRoute.DELETE("/likes", middleware.RequireAuth, controller.UnlikePost)
+		postIdRoute.DELETE("/likes", middleware.RequireAuth, middleware.RequireScope(policy.ScopeLikesWrite), middleware.RequireMFA, controller.UnlikePost)
+
+		// Get the revisions of a specific post
+		postIdRoute.GET("/revisions", middleware.RequireAuth, middleware.RequireScope(policy.ScopePostsRead), controller.ListPostRevisions)
+		// Compare two revisions of a specific post
+		postIdRoute.GET("/revisions/diff", middleware.RequireAuth, middleware.RequireScope(policy.ScopePostsRead), controller.DiffPostRevisions)
+		// Get a specific revision of a specific post
+		postIdRoute.GET("/revisions/:revision", middleware.RequireAuth, middleware.RequireScope(policy.ScopePostsRead), controller.GetPostRevision)
+		// Restore a specific revision of a specific post
+		postIdRoute.POST("/revisions/:revision/restore", middleware.RequireAuth, middleware.RequireScope(policy.ScopePostsWrite), middleware.RequireMFA, controller.RestorePostRevision)
+	}
+
+	// Initialize the categories endpoints
+	categories := v1.Group("/categories")
+	{
+		// Get all the categories
+		categories.GET("", controller.ListCategories)
+		// Get a specific category
+		categories.GET("/:categoryId", controller.GetCategory)
+		// Create a new category
+		categories.POST("", middleware.RequireAuth, middleware.RequireSession, middleware.RequireMFA, middleware.RequirePermission(policy.ManageTaxonomy), controller.CreateCategory)
+		// Rename a specific category
+		categories.PATCH("/:categoryId", middleware.RequireAuth, middleware.RequireSession, middleware.RequireMFA, middleware.RequirePermission(policy.ManageTaxonomy), controller.UpdateCategory)
+		// Delete a specific category
+		categories.DELETE("/:categoryId", middleware.RequireAuth, middleware.RequireSession, middleware.RequireMFA, middleware.RequirePermission(policy.ManageTaxonomy), controller.DeleteCategory)
+		// Merge other categories into a specific category
+		categories.POST("/:categoryId/merge", middleware.RequireAuth, middleware.RequireSession, middleware.RequireMFA, middleware.RequirePermission(policy.ManageTaxonomy), controller.MergeCategories)
+	}
+
+	// Initialize the tags endpoints
+	tags := v1.Group("/tags")
+	{
+		// Get all the tags
+		tags.GET("", controller.ListTags)
+		// Get a specific tag
+		tags.GET("/:tagId", controller.GetTag)
+		// Create a new tag
+		tags.POST("", middleware.RequireAuth, middleware.RequireSession, middleware.RequireMFA, middleware.RequirePermission(policy.ManageTaxonomy), controller.CreateTag)
+		// Rename a specific tag
+		tags.PATCH("/:tagId", middleware.RequireAuth, middleware.RequireSession, middleware.RequireMFA, middleware.RequirePermission(policy.ManageTaxonomy), controller.UpdateTag)
+		// Delete a specific tag
+		tags.DELETE("/:tagId", middleware.RequireAuth, middleware.RequireSession, middleware.RequireMFA, middleware.RequirePermission(policy.ManageTaxonomy), controller.DeleteTag)
+		// Merge other tags into a specific tag
+		tags.POST("/:tagId/merge", middleware.RequireAuth, middleware.RequireSession, middleware.RequireMFA, middleware.RequirePermission(policy.ManageTaxonomy), controller.MergeTags)
+	}
+
+	// Initialize the admin endpoints
+	admin := v1.Group("/admin", middleware.RequireAuth, middleware.RequireSession, middleware.RequireMFA, middleware.RequirePermission(policy.ManageUsers))
+	{
+		// Get all the users
+		admin.GET("/users", controller.ListUsers)
+		// Change the role of a specific user
+		admin.PATCH("/users/:userId/role", controller.UpdateUserRole)
+		// Lift the login lockout of a specific user
+		admin.POST("/users/:userId/unlock", controller.UnlockUser)
+		// Get the authentication event log
+		admin.GET("/auth-events", controller.ListAuthEvents)
+		// Act as a specific user
+		admin.POST("/users/:userId/impersonate", controller.ImpersonateUser)
+		// Get the writes made while impersonating
+		admin.GET("/audit-log", controller.ListAuditLog)
+		// Get all the roles with their permissions
+		admin.GET("/roles", controller.ListRoles)
 	}
 
 	// Set the trusted proxies
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
+)
+
+// RequirePermission returns a middleware that only lets the request through
+// if the authenticated user's role grants the given permission.
+// It must be used after RequireAuth.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exist := c.Get("user")
+		if !exist {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		userModel, ok := user.(models.User)
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if !policy.Can(userModel, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail only lets the request through if the authenticated
+// user has verified their email address. It must be used after RequireAuth.
+func RequireVerifiedEmail(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if !userModel.IsVerified() {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+		return
+	}
+	c.Next()
+}
+
+// RequireMFA rejects requests from users whose role requires two-factor
+// authentication unless the current session was started with a second factor.
+// It must be used after RequireAuth.
+func RequireMFA(c *gin.Context) {
+	user, exist := c.Get("user")
+	if !exist {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	userModel, ok := user.(models.User)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if policy.RequiresMFA(userModel) && !c.GetBool("mfa_verified") {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":        "Two-factor authentication required",
+			"mfa_enrolled": userModel.TOTPEnabled,
+		})
+		return
+	}
+	c.Next()
+}
+
+// RequireScope returns a middleware that checks the scope of requests made with
+// a personal access token. Requests authenticated with a JWT are not limited
+// by scopes. It must be used after RequireAuth.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, isToken := c.Get("token_scopes")
+		if !isToken {
+			c.Next()
+			return
+		}
+
+		scopeList, _ := scopes.([]string)
+		if !policy.HasScope(scopeList, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token is missing the " + scope + " scope"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects requests made with a personal access token or an
+// impersonation token. It protects account management endpoints that only
+// the account owner may reach. It must be used after RequireAuth.
+func RequireSession(c *gin.Context) {
+	if _, isToken := c.Get("token_scopes"); isToken {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Personal access tokens cannot be used for this endpoint"})
+		return
+	}
+	if _, impersonating := c.Get("impersonator"); impersonating {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This endpoint is not available while impersonating"})
+		return
+	}
+	c.Next()
+}
@@ -1,57 +1,145 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
-	"os"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/khunaungpaing/the-blog-api/auth"
 	"github.com/khunaungpaing/the-blog-api/initializer"
 	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/khunaungpaing/the-blog-api/policy"
 )
 
 // RequireAuth gets the bearer token from the request header and verifies its validity.
-// If the token is valid, it sets the user information in the context and continues the request.
+// The token is either a JWT access token whose session has not been revoked or a
+// personal access token. If the token is valid, it sets the user information in the
+// context and continues the request. Personal access tokens additionally set their
+// scopes, which are checked by RequireScope. Impersonation tokens set the
+// impersonated user as "user" and the admin as "impersonator", and every write
+// made with them is recorded in the audit log.
 // If the token is invalid or missing, it returns an unauthorized status.
 func RequireAuth(c *gin.Context) {
+	authenticate(c)
+}
+
+// OptionalAuth authenticates the request like RequireAuth if it has an
+// Authorization header and lets anonymous requests through otherwise.
+func OptionalAuth(c *gin.Context) {
+	if c.GetHeader("Authorization") == "" {
+		c.Next()
+		return
+	}
+	authenticate(c)
+}
+
+func authenticate(c *gin.Context) {
 	// get bearer token
 	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
+	tokenString, found := strings.CutPrefix(authHeader, "Bearer ")
+	if !found || tokenString == "" {
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
-	tokenString := strings.Split(authHeader, " ")[1]
+	if auth.IsPersonalAccessToken(tokenString) {
+		requirePersonalAccessToken(c, tokenString)
+		return
+	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("SECRET_KEY")), nil
-	})
+	claims, err := auth.ParseAccessToken(tokenString)
 	if err != nil {
 		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, ok := claims["sid"].(float64)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	session, ok := auth.ActiveSession(uint(sessionID))
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
 	}
+	auth.TouchSession(session, c.ClientIP())
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
+	userID, err := auth.SubjectID(claims)
+	if err != nil {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
 
-		if float64(time.Now().Unix()) > claims["exp"].(float64) {
-			c.AbortWithStatus(http.StatusUnauthorized)
-		}
+	var user models.User
+	initializer.DB.Preload("Role.Permissions").First(&user, userID)
 
-		var user models.User
-		initializer.DB.First(&user, "email=?", claims["sub"])
+	if user.ID == 0 {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
 
-		if user.ID == 0 {
+	actorID, impersonating, err := auth.ActorID(claims)
+	if err != nil {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if !impersonating {
+		if session.UserID != user.ID {
 			c.AbortWithStatus(http.StatusUnauthorized)
+			return
 		}
 		c.Set("user", user)
+		c.Set("session_id", session.ID)
+		c.Set("mfa_verified", session.MFAVerified)
 		c.Next()
-	} else {
-		fmt.Println(err)
+		return
+	}
+
+	// The session belongs to the admin, who must still be allowed to impersonate
+	var admin models.User
+	initializer.DB.Preload("Role.Permissions").First(&admin, actorID)
+	if admin.ID == 0 || session.UserID != admin.ID || !policy.Can(admin, policy.ManageUsers) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
 	}
+	c.Set("user", user)
+	c.Set("impersonator", admin)
+	c.Set("session_id", session.ID)
+	c.Set("mfa_verified", session.MFAVerified)
+	c.Next()
 
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		auth.RecordAuditLog(models.AuditLog{
+			ImpersonatorID: admin.ID,
+			UserID:         user.ID,
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			Status:         c.Writer.Status(),
+			IP:             c.ClientIP(),
+		})
+	}
+}
+
+func requirePersonalAccessToken(c *gin.Context, tokenString string) {
+	pat, err := auth.AuthenticatePersonalAccessToken(tokenString)
+	if err != nil {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	initializer.DB.Preload("Role.Permissions").First(&user, pat.UserID)
+
+	if user.ID == 0 {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	c.Set("user", user)
+	c.Set("token_id", pat.ID)
+	c.Set("token_scopes", pat.ScopeList())
+	c.Set("mfa_verified", pat.MFAVerified)
+	c.Next()
 }
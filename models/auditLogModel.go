@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// AuditLog records a write made by an admin while impersonating a user.
+type AuditLog struct {
+	gorm.Model
+	ImpersonatorID uint   `json:"impersonator_id" gorm:"index"` // The admin who made the request
+	UserID         uint   `json:"user_id" gorm:"index"`         // The impersonated user
+	Method         string `json:"method"`
+	Path           string `json:"path"`
+	Status         int    `json:"status"`
+	IP             string `json:"ip"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "gorm.io/gorm"
+
+// Authentication event types recorded in the auth event log.
+const (
+	AuthEventLoginSuccess      = "login_success"
+	AuthEventLoginFailure      = "login_failure"
+	AuthEventMFAFailure        = "mfa_failure"
+	AuthEventLoginBlocked      = "login_blocked"
+	AuthEventAccountLocked     = "account_locked"
+	AuthEventAccountUnlocked   = "account_unlocked"
+	AuthEventDeletionRequested = "account_deletion_requested"
+	AuthEventDeletionCancelled = "account_deletion_cancelled"
+	AuthEventAccountDeleted    = "account_deleted"
+	AuthEventImpersonation     = "impersonation_started"
+)
+
+// AuthEvent is an entry of the authentication event log.
+type AuthEvent struct {
+	gorm.Model
+	UserID    *uint  `json:"user_id" gorm:"index"` // Nil if the email does not belong to an account
+	Email     string `json:"email" gorm:"index"`
+	IP        string `json:"ip" gorm:"index"`
+	UserAgent string `json:"user_agent"`
+	Event     string `json:"event" gorm:"index"`
+	Reason    string `json:"reason,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Block stops a user from commenting on or liking the blocker's posts and
+// hides the blocked user's posts and comments from the blocker.
+type Block struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	BlockerID uint      `json:"blocker_id" gorm:"uniqueIndex:idx_blocker_blocked"`
+	BlockedID uint      `json:"blocked_id" gorm:"uniqueIndex:idx_blocker_blocked;index"`
+}
+
+// Mute hides the muted user's posts and comments from the muter.
+type Mute struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	MuterID   uint      `json:"muter_id" gorm:"uniqueIndex:idx_muter_muted"`
+	MutedID   uint      `json:"muted_id" gorm:"uniqueIndex:idx_muter_muted"`
+}
@@ -8,4 +8,5 @@ type Comment struct {
 	PostID  uint   `json:"post_id"`
 	UserID  uint   `json:"user_id"` // Optional
 	Content string `json:"content"`
+	Version uint   `json:"version" gorm:"not null;default:1"` // Incremented on every change, sent as the ETag
 }
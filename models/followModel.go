@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Follow records that a user follows an author. Unfollowing deletes the row.
+type Follow struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"created_at"`
+	FollowerID uint      `json:"follower_id" gorm:"uniqueIndex:idx_follower_followee"`
+	FolloweeID uint      `json:"followee_id" gorm:"uniqueIndex:idx_follower_followee;index"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invitation is a code that lets people sign up while registration is
+// invite-only. Only the SHA-256 hash of the code is stored.
+type Invitation struct {
+	gorm.Model
+	CreatedByID uint       `json:"created_by_id" gorm:"index"`
+	CodeHash    string     `json:"-" gorm:"uniqueIndex"`
+	Prefix      string     `json:"prefix"`         // First characters of the code, to recognise it in listings
+	Email       string     `json:"email"`          // Optional, only this address can use the code
+	RoleID      *uint      `json:"role_id"`        // Optional role for the new users, nil means the default role
+	Role        *Role      `json:"role,omitempty"` // Preloaded in listings
+	MaxUses     int        `json:"max_uses"`
+	UseCount    int        `json:"use_count"`
+	ExpiresAt   *time.Time `json:"expires_at"` // Optional, nil means the code does not expire
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateInvitationRequest struct {
+	Email     string     `json:"email"`    // Optional email address the code is tied to
+	Role      string     `json:"role"`     // Optional role name, requires the users:manage permission
+	MaxUses   int        `json:"max_uses"` // Defaults to 1
+	ExpiresAt *time.Time `json:"expires_at"`
+}
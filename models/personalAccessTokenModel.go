@@ -0,0 +1,37 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PersonalAccessToken is a long-lived token for scripts and CI with a limited
+// set of scopes. Only the SHA-256 hash of the token is stored.
+type PersonalAccessToken struct {
+	gorm.Model
+	UserID      uint       `json:"user_id" gorm:"index"`
+	Name        string     `json:"name"`
+	TokenHash   string     `json:"-" gorm:"uniqueIndex"`
+	Prefix      string     `json:"prefix"`       // First characters of the token, to recognise it in listings
+	Scopes      string     `json:"scopes"`       // Comma separated scopes, e.g. posts:write,comments:read
+	MFAVerified bool       `json:"mfa_verified"` // Whether the token was created from a session that used a second factor
+	ExpiresAt   *time.Time `json:"expires_at"`   // Optional, nil means the token does not expire
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ScopeList returns the scopes of the token as a slice.
+func (t PersonalAccessToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return nil
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+type CreateTokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"` // Optional expiry
+}
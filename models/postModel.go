@@ -1,35 +1,62 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// Post content formats, see render.HTML.
+const (
+	ContentFormatMarkdown = "markdown"
+	ContentFormatHTML     = "html"
+	ContentFormatPlain    = "plain"
+)
+
+// Post statuses, see policy.PostTransitions for the allowed changes.
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
 type Post struct {
 	gorm.Model
-	UserID     uint       `json:"user_id"`
-	Title      string     `json:"title"`
-	Content    string     `json:"content"`            // Can include HTML for formatting
-	Slug       string     `json:"slug" gorm:"unique"` // Unique URL slug for SEO
-	Status     string     `json:"status" gorm:"type:string;check:status IN ('draft', 'published', 'archived')"`
-	Categories []Category `gorm:"many2many:post_categories"` // Optional, Many-to-Many relationship with Category (using a join table)
-	Tags       []Tag      `gorm:"many2many:post_tags"`       // Optional, Many-to-Many relationship with Tag (using a join table)
-	Comments   []Comment  `gorm:"foreignKey:PostID"`         // One-to-Many relationship with Comment
-	Media      *Media     `gorm:"foreignKey:PostID"`         // Optional, One-to-One or One-to-Many relationship with Media
-	User       *User      `json:",omitempty" gorm:"foreignKey:UserID"`
+	UserID        uint       `json:"user_id"`
+	Title         string     `json:"title"`
+	Content       string     `json:"content"`                                                                                             // Source in ContentFormat
+	ContentFormat string     `json:"content_format" gorm:"not null;default:'html';check:content_format IN ('markdown', 'html', 'plain')"` // markdown, html or plain
+	ContentHTML   string     `json:"content_html" gorm:"not null;default:''"`                                                             // Sanitized HTML rendered from Content on save
+	Slug          string     `json:"slug" gorm:"unique"`                                                                                  // Unique URL slug for SEO
+	Status        string     `json:"status" gorm:"type:string;check:chk_posts_status_workflow,status IN ('draft', 'scheduled', 'published', 'archived')"`
+	PublishAt     *time.Time `json:"publish_at" gorm:"index"`                                                // When a scheduled post goes live
+	PublishedAt   *time.Time `json:"published_at"`                                                           // Set when the post is first published
+	Version       uint       `json:"version" gorm:"not null;default:1"`                                      // Incremented on every change, sent as the ETag
+	SearchVector  string     `json:"-" gorm:"type:tsvector;index:idx_posts_search_vector,type:gin;->:false"` // Maintained by database triggers, see initializer.setupPostSearch
+	Categories    []Category `gorm:"many2many:post_categories"`                                              // Optional, Many-to-Many relationship with Category (using a join table)
+	Tags          []Tag      `gorm:"many2many:post_tags"`                                                    // Optional, Many-to-Many relationship with Tag (using a join table)
+	Comments      []Comment  `gorm:"foreignKey:PostID"`                                                      // One-to-Many relationship with Comment
+	Media         *Media     `gorm:"foreignKey:PostID"`                                                      // Optional, One-to-One or One-to-Many relationship with Media
+	User          *User      `json:",omitempty" gorm:"foreignKey:UserID"`
 }
 
 type Category struct {
 	gorm.Model
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Posts       []Post `gorm:"many2many:post_categories"` // Optional, Many-to-Many relationship with Post (using a join table)
+	Name           string `json:"name"`
+	NormalizedName string `json:"-" gorm:"uniqueIndex:idx_categories_normalized_name,where:deleted_at IS NULL"` // See taxonomy.Normalize
+	MergedIntoID   *uint  `json:"-" gorm:"index"`                                                               // Set when the category was merged into another one
+	Description    string `json:"description"`
+	Posts          []Post `gorm:"many2many:post_categories"` // Optional, Many-to-Many relationship with Post (using a join table)
 }
 
 type Tag struct {
 	gorm.Model
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Posts       []Post `gorm:"many2many:post_tags"` // Optional, Many-to-Many relationship with Post (using a join table)
+	Name           string `json:"name"`
+	NormalizedName string `json:"-" gorm:"uniqueIndex:idx_tags_normalized_name,where:deleted_at IS NULL"` // See taxonomy.Normalize
+	MergedIntoID   *uint  `json:"-" gorm:"index"`                                                         // Set when the tag was merged into another one
+	Description    string `json:"description"`
+	Posts          []Post `gorm:"many2many:post_tags"` // Optional, Many-to-Many relationship with Post (using a join table)
 }
 
 type Media struct {
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// PostRevision is an immutable snapshot of a post, saved every time the post
+// is created, updated or restored.
+type PostRevision struct {
+	ID            uint           `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time      `json:"created_at"`
+	PostID        uint           `json:"post_id" gorm:"uniqueIndex:idx_post_revisions_number"`
+	Number        int            `json:"number" gorm:"uniqueIndex:idx_post_revisions_number"` // Counts up from 1 for each post
+	AuthorID      uint           `json:"author_id"`                                           // The user who made the change
+	Title         string         `json:"title"`
+	Content       string         `json:"content"`
+	ContentFormat string         `json:"content_format" gorm:"not null;default:'html'"`
+	Categories    []RevisionTerm `json:"categories" gorm:"serializer:json"`
+	Tags          []RevisionTerm `json:"tags" gorm:"serializer:json"`
+	RestoredFrom  *int           `json:"restored_from,omitempty"` // The revision this one restored
+	Author        *User          `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+}
+
+// RevisionTerm is a category or tag as it was when a revision was saved.
+type RevisionTerm struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PostSlug is a slug a post used to have. Requests for it are redirected to
+// the post's current slug.
+type PostSlug struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	PostID    uint      `json:"post_id" gorm:"index"`
+	Slug      string    `json:"slug" gorm:"uniqueIndex"`
+}
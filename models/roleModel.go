@@ -0,0 +1,22 @@
+package models
+
+import "gorm.io/gorm"
+
+// Role groups a set of permissions that can be granted to users.
+type Role struct {
+	gorm.Model
+	Name        string       `json:"name" gorm:"unique"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions,omitempty" gorm:"many2many:role_permissions"` // Many-to-Many relationship with Permission
+}
+
+// Permission is a single action a role is allowed to perform (e.g. posts:update_any).
+type Permission struct {
+	gorm.Model
+	Name        string `json:"name" gorm:"unique"`
+	Description string `json:"description"`
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session represents a login session. Every access token carries the ID of
+// the session it was issued for, so revoking the session invalidates them.
+type Session struct {
+	gorm.Model
+	UserID      uint       `json:"user_id" gorm:"index"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	MFAVerified bool       `json:"mfa_verified"` // Whether a second factor was used to log in
+	UserAgent   string     `json:"user_agent"`   // User agent of the device that logged in
+	IP          string     `json:"ip"`           // IP address the session was last used from
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	Current     bool       `json:"current" gorm:"-"` // Set in listings for the session making the request
+}
+
+// RefreshToken is a single-use token that can be exchanged for a new access
+// token. Only the SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	gorm.Model
+	SessionID uint       `json:"session_id" gorm:"index"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
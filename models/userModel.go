@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
@@ -10,6 +14,33 @@ type User struct {
 	Bio        string `json:"bio"`                                      // Optional user bio
 	ProfilePic string `json:"profile_pic"`                              // Optional profile picture URL
 	Posts      []Post `json:"posts,omitempty" gorm:"foreignKey:UserID"` // One-to-Many relationship with Post
+	RoleID     uint   `json:"role_id"`                                  // Role granting the user's permissions
+	Role       *Role  `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`       // Nil until the email address is verified
+	PendingEmail    string     `json:"pending_email,omitempty"` // New email address waiting for verification
+
+	TOTPSecret   string `json:"-"`            // Base32 TOTP secret, set during enrolment
+	TOTPEnabled  bool   `json:"totp_enabled"` // Whether two-factor authentication is active
+	TOTPLastStep int64  `json:"-"`            // Time step of the last accepted code, prevents replays
+
+	FailedLoginAttempts int        `json:"-"`                      // Failed logins since the last success or lockout
+	LockoutCount        int        `json:"-"`                      // Consecutive lockouts, used for the backoff
+	LockedUntil         *time.Time `json:"locked_until,omitempty"` // Logins are refused until this time
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"` // The account is purged at this time unless the deletion is cancelled
+	DeletionMode        string     `json:"deletion_mode,omitempty"`         // What happens to the user's content, see DeletionModeAnonymize and DeletionModeCascade
+}
+
+// Account deletion modes.
+const (
+	DeletionModeAnonymize = "anonymize" // Keep posts and comments but strip everything that identifies the user
+	DeletionModeCascade   = "cascade"   // Delete posts, comments and likes along with the account
+)
+
+// IsVerified reports whether the user has verified their email address.
+func (u User) IsVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 type LoginRequest struct {
@@ -17,8 +48,14 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-type RegisterRequest struct {
-	Username string `json:"username" binding:"required"`
-	Email    string `json:"email" binding:"required"`
+type DeleteAccountRequest struct {
 	Password string `json:"password" binding:"required"`
+	Mode     string `json:"mode"` // anonymize (default) or cascade
+}
+
+type RegisterRequest struct {
+	Username       string `json:"username" binding:"required"`
+	Email          string `json:"email" binding:"required"`
+	Password       string `json:"password" binding:"required"`
+	InvitationCode string `json:"invitation_code"` // Required when registration is invite-only
 }
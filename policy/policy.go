@@ -0,0 +1,202 @@
+package policy
+
+import (
+	"os"
+	"strings"
+
+	"github.com/khunaungpaing/the-blog-api/models"
+)
+
+// Permission names stored in the permissions table.
+const (
+	CreatePost       = "posts:create"
+	UpdateOwnPost    = "posts:update_own"
+	UpdateAnyPost    = "posts:update_any"
+	DeleteOwnPost    = "posts:delete_own"
+	DeleteAnyPost    = "posts:delete_any"
+	CreateComment    = "comments:create"
+	UpdateOwnComment = "comments:update_own"
+	DeleteOwnComment = "comments:delete_own"
+	DeleteAnyComment = "comments:delete_any"
+	ManageUsers      = "users:manage"
+	CreateInvitation = "invitations:create"
+	ManageTaxonomy   = "taxonomy:manage"
+)
+
+// Role names stored in the roles table.
+const (
+	RoleAdmin     = "admin"
+	RoleEditor    = "editor"
+	RoleModerator = "moderator"
+	RoleAuthor    = "author"
+	RoleReader    = "reader"
+)
+
+// Scopes that can be granted to personal access tokens.
+const (
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopeCommentsRead  = "comments:read"
+	ScopeCommentsWrite = "comments:write"
+	ScopeLikesRead     = "likes:read"
+	ScopeLikesWrite    = "likes:write"
+	ScopeProfileRead   = "profile:read"
+)
+
+// Scopes describes every scope a personal access token can have.
+var Scopes = map[string]string{
+	ScopePostsRead:     "Read posts",
+	ScopePostsWrite:    "Create, update and delete posts",
+	ScopeCommentsRead:  "Read comments",
+	ScopeCommentsWrite: "Create, update and delete comments",
+	ScopeLikesRead:     "Read likes",
+	ScopeLikesWrite:    "Like and unlike posts",
+	ScopeProfileRead:   "Read the user's profile",
+}
+
+// DefaultRole is the role given to newly registered users.
+const DefaultRole = RoleAuthor
+
+// Permissions describes every permission known to the application.
+var Permissions = map[string]string{
+	CreatePost:       "Create posts",
+	UpdateOwnPost:    "Update own posts",
+	UpdateAnyPost:    "Update any post",
+	DeleteOwnPost:    "Delete own posts",
+	DeleteAnyPost:    "Delete any post",
+	CreateComment:    "Comment on posts",
+	UpdateOwnComment: "Update own comments",
+	DeleteOwnComment: "Delete own comments",
+	DeleteAnyComment: "Delete any comment",
+	ManageUsers:      "Manage users and their roles",
+	CreateInvitation: "Issue invitation codes",
+	ManageTaxonomy:   "Manage categories and tags",
+}
+
+var readerPermissions = []string{CreateComment, UpdateOwnComment, DeleteOwnComment}
+
+var authorPermissions = append([]string{CreatePost, UpdateOwnPost, DeleteOwnPost, CreateInvitation}, readerPermissions...)
+
+// DefaultRoles lists the roles seeded on startup together with their permissions.
+var DefaultRoles = map[string][]string{
+	RoleReader:    readerPermissions,
+	RoleAuthor:    authorPermissions,
+	RoleModerator: append([]string{DeleteAnyComment}, authorPermissions...),
+	RoleEditor:    append([]string{UpdateAnyPost, ManageTaxonomy}, authorPermissions...),
+	RoleAdmin: {
+		CreatePost, UpdateOwnPost, UpdateAnyPost, DeleteOwnPost, DeleteAnyPost,
+		CreateComment, UpdateOwnComment, DeleteOwnComment, DeleteAnyComment,
+		ManageUsers, CreateInvitation, ManageTaxonomy,
+	},
+}
+
+// Can reports whether the user's role grants the permission.
+// The user's role and its permissions must be preloaded.
+func Can(user models.User, permission string) bool {
+	if user.Role == nil {
+		return false
+	}
+	for _, p := range user.Role.Permissions {
+		if p.Name == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether the user has the named role.
+func HasRole(user models.User, role string) bool {
+	return user.Role != nil && user.Role.Name == role
+}
+
+// RequiresMFA reports whether the user's role has to log in with a second
+// factor. The roles are read from MFA_REQUIRED_ROLES (default "admin,editor").
+func RequiresMFA(user models.User) bool {
+	roles := os.Getenv("MFA_REQUIRED_ROLES")
+	if roles == "" {
+		roles = RoleAdmin + "," + RoleEditor
+	}
+	for _, role := range strings.Split(roles, ",") {
+		if HasRole(user, strings.TrimSpace(role)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanUpdatePost reports whether the user may update the post.
+func CanUpdatePost(user models.User, post models.Post) bool {
+	if post.UserID == user.ID && Can(user, UpdateOwnPost) {
+		return true
+	}
+	return Can(user, UpdateAnyPost)
+}
+
+// CanDeletePost reports whether the user may delete the post.
+func CanDeletePost(user models.User, post models.Post) bool {
+	if post.UserID == user.ID && Can(user, DeleteOwnPost) {
+		return true
+	}
+	return Can(user, DeleteAnyPost)
+}
+
+// CanUpdateComment reports whether the user may update the comment.
+// Only the author of a comment can edit it.
+func CanUpdateComment(user models.User, comment models.Comment) bool {
+	return comment.UserID == user.ID && Can(user, UpdateOwnComment)
+}
+
+// CanDeleteComment reports whether the user may delete the comment on the post.
+// The author of the comment, the owner of the post and moderators can delete it.
+func CanDeleteComment(user models.User, comment models.Comment, post models.Post) bool {
+	if comment.UserID == user.ID && Can(user, DeleteOwnComment) {
+		return true
+	}
+	if post.UserID == user.ID {
+		return true
+	}
+	return Can(user, DeleteAnyComment)
+}
+
+// PostTransitions lists the statuses a post may move to from each status.
+// New posts start as a draft, scheduled or published right away. Scheduled
+// posts are published by the scheduler once their publish_at is due.
+var PostTransitions = map[string][]string{
+	models.PostStatusDraft:     {models.PostStatusPublished, models.PostStatusScheduled, models.PostStatusArchived},
+	models.PostStatusScheduled: {models.PostStatusDraft, models.PostStatusPublished},
+	models.PostStatusPublished: {models.PostStatusDraft, models.PostStatusArchived},
+	models.PostStatusArchived:  {models.PostStatusDraft},
+}
+
+// CanTransitionPost reports whether a post may move from one status to another.
+func CanTransitionPost(from, to string) bool {
+	for _, status := range PostTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CanViewPost reports whether the user may see the post. Published posts are
+// public; drafts and archived posts are only visible to their author and to
+// editors. Pass a zero User for anonymous readers.
+func CanViewPost(user models.User, post models.Post) bool {
+	if post.Status == models.PostStatusPublished {
+		return true
+	}
+	if user.ID != 0 && post.UserID == user.ID {
+		return true
+	}
+	return Can(user, UpdateAnyPost)
+}
+
+// HasScope reports whether the scope is in the list of granted scopes.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
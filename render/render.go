@@ -0,0 +1,89 @@
+// Package render turns post content into sanitized HTML.
+package render
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/khunaungpaing/the-blog-api/models"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// ErrUnknownFormat is returned for content formats other than markdown, html
+// and plain.
+var ErrUnknownFormat = errors.New("unknown content format")
+
+// markdown renders GitHub Flavored Markdown (tables, task lists,
+// strikethrough and autolinks) with footnotes. Fenced code blocks get a
+// language-<name> class for client-side syntax highlighting. Raw HTML is
+// passed through and cleaned up by the sanitizer like any other HTML.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+)
+
+var sanitizer = newSanitizer()
+
+// newSanitizer allows the elements and attributes of user generated content,
+// plus the classes and roles markdown code blocks, footnotes and task lists
+// are rendered with. Scripts, styles, event handlers and javascript: URLs
+// are removed.
+func newSanitizer() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^footnote-(ref|backref)$`)).OnElements("a")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^footnotes$`)).OnElements("div")
+	policy.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-(noteref|backlink|endnotes)$`)).OnElements("a", "div")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	return policy
+}
+
+var blankLines = regexp.MustCompile(`\n[ \t]*\n`)
+
+// HTML renders content written in the given format as sanitized HTML.
+func HTML(format, source string) (string, error) {
+	switch format {
+	case models.ContentFormatMarkdown:
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(source), &buf); err != nil {
+			return "", err
+		}
+		return Sanitize(buf.String()), nil
+	case models.ContentFormatHTML:
+		return Sanitize(source), nil
+	case models.ContentFormatPlain:
+		return plainText(source), nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// Sanitize removes everything from HTML that is not on the allow-list.
+func Sanitize(source string) string {
+	return sanitizer.Sanitize(source)
+}
+
+// plainText escapes text and turns blank-line separated blocks into
+// paragraphs and the remaining line breaks into <br>.
+func plainText(text string) string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	var b strings.Builder
+	for _, paragraph := range blankLines.Split(text, -1) {
+		if paragraph = strings.TrimSpace(paragraph); paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
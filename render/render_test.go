@@ -0,0 +1,69 @@
+package render
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/khunaungpaing/the-blog-api/models"
+)
+
+func TestHTMLRemovesDangerousMarkup(t *testing.T) {
+	tests := []struct {
+		name, format, source, forbidden string
+	}{
+		{"script in html", models.ContentFormatHTML, `<p>hi</p><script>alert(1)</script>`, "<script"},
+		{"script in markdown", models.ContentFormatMarkdown, "hi\n\n<script>alert(1)</script>", "<script"},
+		{"javascript link in html", models.ContentFormatHTML, `<a href="javascript:alert(1)">x</a>`, "javascript:"},
+		{"javascript link in markdown", models.ContentFormatMarkdown, `[x](javascript:alert(1))`, "javascript:"},
+		{"onerror in html", models.ContentFormatHTML, `<img src="x.png" onerror="alert(1)">`, "onerror"},
+		{"onerror in markdown", models.ContentFormatMarkdown, `<img src="x.png" onerror="alert(1)">`, "onerror"},
+		{"style", models.ContentFormatHTML, `<style>body{display:none}</style><p>x</p>`, "<style"},
+		{"iframe", models.ContentFormatHTML, `<iframe src="https://example.com"></iframe>`, "<iframe"},
+		{"markup in plain text", models.ContentFormatPlain, `<script>alert(1)</script>`, "<script"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTML(tt.format, tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(strings.ToLower(got), tt.forbidden) {
+				t.Errorf("HTML(%q) = %q, contains %q", tt.source, got, tt.forbidden)
+			}
+		})
+	}
+}
+
+func TestHTMLKeepsAllowedMarkup(t *testing.T) {
+	tests := []struct {
+		name, format, source, want string
+	}{
+		{"emphasis", models.ContentFormatMarkdown, "*hi*", "<em>hi</em>"},
+		{"code language", models.ContentFormatMarkdown, "```go\nx := 1\n```", `<code class="language-go">`},
+		{"table", models.ContentFormatMarkdown, "| a |\n| - |\n| b |", "<table>"},
+		{"strikethrough", models.ContentFormatMarkdown, "~~old~~", "<del>old</del>"},
+		{"task list", models.ContentFormatMarkdown, "- [x] done", `type="checkbox"`},
+		{"footnote", models.ContentFormatMarkdown, "Text[^1]\n\n[^1]: Note", `class="footnotes"`},
+		{"safe link", models.ContentFormatHTML, `<a href="https://example.com">x</a>`, `href="https://example.com"`},
+		{"plain paragraphs", models.ContentFormatPlain, "one\ntwo\n\nthree", "<p>one<br>\ntwo</p>\n<p>three</p>"},
+		{"plain escaping", models.ContentFormatPlain, "a < b & c", "a &lt; b &amp; c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTML(tt.format, tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("HTML(%q) = %q, want it to contain %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLUnknownFormat(t *testing.T) {
+	if _, err := HTML("rtf", "x"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("err = %v, want ErrUnknownFormat", err)
+	}
+}
@@ -0,0 +1,81 @@
+// Package scheduler runs the background jobs that act on posts.
+package scheduler
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/khunaungpaing/the-blog-api/initializer"
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPublishInterval = 30 * time.Second
+
+	// publishBatchSize is how many posts one transaction publishes.
+	publishBatchSize = 100
+)
+
+// PublishInterval returns how often scheduled posts are checked, read from
+// the SCHEDULED_POSTS_INTERVAL environment variable.
+func PublishInterval() time.Duration {
+	if value := os.Getenv("SCHEDULED_POSTS_INTERVAL"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultPublishInterval
+}
+
+// StartPostPublisher publishes the scheduled posts that are due every
+// interval. It blocks, so run it in its own goroutine.
+func StartPostPublisher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := PublishDuePosts(); err != nil {
+			log.Printf("Failed to publish scheduled posts: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// PublishDuePosts publishes every scheduled post whose publish_at has passed
+// and returns how many were published. Rows are locked with SKIP LOCKED so
+// several replicas can run the publisher at once without publishing a post
+// twice.
+func PublishDuePosts() (int, error) {
+	total := 0
+	for {
+		var published int
+		err := initializer.DB.Transaction(func(tx *gorm.DB) error {
+			var ids []uint
+			if err := tx.Model(&models.Post{}).
+				Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("status = ? AND publish_at <= ?", models.PostStatusScheduled, time.Now()).
+				Order("publish_at").
+				Limit(publishBatchSize).
+				Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+				return err
+			}
+
+			// Posts go live at their scheduled time, not when the publisher ran
+			result := tx.Model(&models.Post{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{
+				"status":       models.PostStatusPublished,
+				"published_at": gorm.Expr("COALESCE(published_at, publish_at)"),
+				"publish_at":   nil,
+				"version":      gorm.Expr("version + 1"),
+				"updated_at":   time.Now(),
+			})
+			published = int(result.RowsAffected)
+			return result.Error
+		})
+		total += published
+		if err != nil || published == 0 {
+			return total, err
+		}
+	}
+}
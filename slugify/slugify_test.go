@@ -0,0 +1,42 @@
+package slugify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"ascii", "Hello, World!", "hello-world"},
+		{"accents", "Crème Brûlée & Co.", "creme-brulee-and-co"},
+		{"apostrophes", "Don't stop", "dont-stop"},
+		{"sharp s", "Straße", "strasse"},
+		{"ligature", "ﬁx", "fix"},
+		{"cyrillic letters with own transliteration", "Ёлка йод", "yolka-yod"},
+		{"ukrainian", "Їжак", "yizhak"},
+		{"decomposed input", "\u0415\u0308", "yo"},
+		{"greek", "Ἀθῆναι", "athinai"},
+		{"cjk is dropped", "日本語", ""},
+		{"only punctuation", "!!!", ""},
+		{"collapses separators", "  a -- b  ", "a-b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Make(tt.text); got != tt.want {
+				t.Errorf("Make(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeMaxLength(t *testing.T) {
+	slug := Make(strings.Repeat("word ", 40))
+	if len(slug) > MaxLength {
+		t.Fatalf("len(slug) = %d, want at most %d", len(slug), MaxLength)
+	}
+	if strings.HasSuffix(slug, "-") || !strings.HasSuffix(slug, "word") {
+		t.Errorf("slug %q should end on a whole word", slug)
+	}
+}
@@ -0,0 +1,95 @@
+// Package taxonomy keeps the names of categories and tags unique and merges
+// duplicates.
+package taxonomy
+
+import (
+	"strings"
+
+	"github.com/khunaungpaing/the-blog-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kind describes the table of categories or tags and the join table that
+// attaches them to posts.
+type Kind struct {
+	New        func() interface{} // Returns a new *models.Category or *models.Tag
+	Noun       string             // "Category" or "Tag", for messages
+	Table      string
+	JoinTable  string
+	JoinColumn string // Column of the join table that references Table
+}
+
+var (
+	Categories = Kind{
+		New:        func() interface{} { return &models.Category{} },
+		Noun:       "Category",
+		Table:      "categories",
+		JoinTable:  "post_categories",
+		JoinColumn: "category_id",
+	}
+	Tags = Kind{
+		New:        func() interface{} { return &models.Tag{} },
+		Noun:       "Tag",
+		Table:      "tags",
+		JoinTable:  "post_tags",
+		JoinColumn: "tag_id",
+	}
+)
+
+// CleanName trims a name and collapses runs of whitespace, e.g. "  Go   tips"
+// becomes "Go tips".
+func CleanName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Normalize returns the form names are compared in: cleaned and lowercase,
+// so "Go Tips" and " go  tips" are the same name.
+func Normalize(name string) string {
+	return strings.ToLower(CleanName(name))
+}
+
+// Merge moves the posts of the source categories or tags to the target and
+// deletes the sources. Posts that already have the target keep it once. The
+// sources, and whatever was merged into them before, remember the target so
+// that old references can be resolved with Successors.
+func Merge(tx *gorm.DB, kind Kind, targetID uint, sourceIDs []uint) error {
+	if len(sourceIDs) == 0 {
+		return nil
+	}
+	if err := tx.Exec(
+		"INSERT INTO "+kind.JoinTable+" (post_id, "+kind.JoinColumn+") "+
+			"SELECT DISTINCT post_id, ? FROM "+kind.JoinTable+" WHERE "+kind.JoinColumn+" IN ? ON CONFLICT DO NOTHING",
+		targetID, sourceIDs,
+	).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM "+kind.JoinTable+" WHERE "+kind.JoinColumn+" IN ?", sourceIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(kind.New()).
+		Where("id IN ? OR merged_into_id IN ?", sourceIDs, sourceIDs).
+		UpdateColumn("merged_into_id", targetID).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", sourceIDs).Delete(kind.New()).Error
+}
+
+// Successors returns the IDs of the categories or tags that exist now for
+// the given ones: the ones that still exist and the targets of the ones that
+// were merged away. Deleted ones are left out.
+func Successors(tx *gorm.DB, kind Kind, ids []uint) ([]uint, error) {
+	merged := tx.Unscoped().Model(kind.New()).Select("merged_into_id").
+		Where("id IN ? AND deleted_at IS NOT NULL AND merged_into_id IS NOT NULL", ids)
+	var current []uint
+	err := tx.Model(kind.New()).Where("id IN ? OR id IN (?)", ids, merged).Order("id").Pluck("id", &current).Error
+	return current, err
+}
+
+// OnNameConflict makes creating a category or tag whose name is taken do
+// nothing, leaving its ID zero.
+var OnNameConflict = clause.OnConflict{
+	Columns:     []clause.Column{{Name: "normalized_name"}},
+	TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+	DoNothing:   true,
+}
@@ -0,0 +1,23 @@
+package taxonomy
+
+import "testing"
+
+func TestCleanNameAndNormalize(t *testing.T) {
+	tests := []struct {
+		name, clean, normalized string
+	}{
+		{"Go", "Go", "go"},
+		{"  Go   Tips ", "Go Tips", "go tips"},
+		{"go\ttips\n", "go tips", "go tips"},
+		{"ÜBER Café", "ÜBER Café", "über café"},
+		{"   ", "", ""},
+	}
+	for _, tt := range tests {
+		if got := CleanName(tt.name); got != tt.clean {
+			t.Errorf("CleanName(%q) = %q, want %q", tt.name, got, tt.clean)
+		}
+		if got := Normalize(tt.name); got != tt.normalized {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.normalized)
+		}
+	}
+}
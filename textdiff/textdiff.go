@@ -0,0 +1,196 @@
+// Package textdiff computes line and word diffs between two texts.
+package textdiff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Chunk operations.
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// maxEdits bounds the search for the shortest diff. Texts that need more
+// edits are reported as deleted and inserted as a whole.
+const maxEdits = 2000
+
+// Chunk is a run of text that is unchanged, inserted or deleted. Joining the
+// equal and delete chunks gives the old text, joining the equal and insert
+// chunks gives the new one.
+type Chunk struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Lines diffs two texts line by line.
+func Lines(a, b string) []Chunk {
+	return diff(splitLines(a), splitLines(b))
+}
+
+// Words diffs two texts word by word. Whitespace and punctuation are kept as
+// tokens of their own, so the chunks add up to the texts exactly.
+func Words(a, b string) []Chunk {
+	return diff(splitWords(a), splitWords(b))
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func splitWords(s string) []string {
+	class := func(r rune) int {
+		switch {
+		case unicode.IsSpace(r):
+			return 0
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	var words []string
+	start, prev := 0, -1
+	for i, r := range s {
+		c := class(r)
+		// Punctuation is split into single characters
+		if i > start && (c != prev || c == 2) {
+			words = append(words, s[start:i])
+			start = i
+		}
+		prev = c
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+type edit struct {
+	op    string
+	token string
+}
+
+func diff(a, b []string) []Chunk {
+	// A common prefix and suffix need no search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	chunks := []Chunk{}
+	for _, token := range a[:prefix] {
+		chunks = appendToken(chunks, Equal, token)
+	}
+	for _, e := range shortestEdit(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		chunks = appendToken(chunks, e.op, e.token)
+	}
+	for _, token := range a[len(a)-suffix:] {
+		chunks = appendToken(chunks, Equal, token)
+	}
+	return chunks
+}
+
+// appendToken adds a token to the last chunk if it has the same operation.
+func appendToken(chunks []Chunk, op, token string) []Chunk {
+	if n := len(chunks); n > 0 && chunks[n-1].Op == op {
+		chunks[n-1].Text += token
+		return chunks
+	}
+	return append(chunks, Chunk{Op: op, Text: token})
+}
+
+// shortestEdit finds the shortest edit script that turns a into b with
+// Myers' O(ND) algorithm. Deletions come before insertions at each change.
+func shortestEdit(a, b []string) []edit {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > maxEdits {
+		limit = maxEdits
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k = x - y
+	offset := limit + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		// Keep diagonals -d..d of the previous round for the backtrack
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d)
+			}
+		}
+	}
+
+	// Too different to diff in reasonable time
+	edits := make([]edit, 0, n+m)
+	for _, token := range a {
+		edits = append(edits, edit{Delete, token})
+	}
+	for _, token := range b {
+		edits = append(edits, edit{Insert, token})
+	}
+	return edits
+}
+
+func backtrack(a, b []string, trace [][]int, d int) []edit {
+	furthest := func(d, k int) int { return trace[d][k+d] }
+
+	var edits []edit
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && furthest(d, k-1) < furthest(d, k+1)) {
+			prevK = k + 1
+		}
+		prevX := furthest(d, prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			edits = append(edits, edit{Insert, b[y-1]})
+		} else {
+			edits = append(edits, edit{Delete, a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		edits = append(edits, edit{Equal, a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
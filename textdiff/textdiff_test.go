@@ -0,0 +1,133 @@
+package textdiff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// texts joins the chunks back into the old and the new text.
+func texts(chunks []Chunk) (string, string) {
+	var older, newer strings.Builder
+	for _, chunk := range chunks {
+		switch chunk.Op {
+		case Equal:
+			older.WriteString(chunk.Text)
+			newer.WriteString(chunk.Text)
+		case Delete:
+			older.WriteString(chunk.Text)
+		case Insert:
+			newer.WriteString(chunk.Text)
+		}
+	}
+	return older.String(), newer.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name, a, b string
+	}{
+		{"empty", "", ""},
+		{"insert into empty", "", "one\ntwo\n"},
+		{"delete all", "one\ntwo\n", ""},
+		{"unchanged", "same\ntext\n", "same\ntext\n"},
+		{"change in the middle", "a\nb\nc\n", "a\nx\nc\n"},
+		{"no trailing newline", "a\nb", "a\nb\nc"},
+		{"words", "The quick brown fox.", "The slow brown dog!"},
+		{"unicode", "Grüße aus Köln", "Grüße aus Münster"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for mode, fn := range map[string]func(a, b string) []Chunk{"lines": Lines, "words": Words} {
+				older, newer := texts(fn(tt.a, tt.b))
+				if older != tt.a || newer != tt.b {
+					t.Errorf("%s: chunks give %q -> %q, want %q -> %q", mode, older, newer, tt.a, tt.b)
+				}
+			}
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	got := Lines("a\nb\nc\n", "a\nx\nc\n")
+	want := []Chunk{{Equal, "a\n"}, {Delete, "b\n"}, {Insert, "x\n"}, {Equal, "c\n"}}
+	if len(got) != len(want) {
+		t.Fatalf("Lines = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chunk %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWordsKeepsWhitespaceAndPunctuation(t *testing.T) {
+	got := Words("Hello, world", "Hello, there")
+	want := []Chunk{{Equal, "Hello, "}, {Delete, "world"}, {Insert, "there"}}
+	if len(got) != len(want) {
+		t.Fatalf("Words = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chunk %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestMinimal checks on random inputs that the diff keeps a longest common
+// subsequence of the lines, i.e. that the backtrack finds a shortest edit.
+func TestMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomText := func() string {
+		lines := make([]string, r.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a'+r.Intn(4))) + "\n"
+		}
+		return strings.Join(lines, "")
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randomText(), randomText()
+		chunks := Lines(a, b)
+		if older, newer := texts(chunks); older != a || newer != b {
+			t.Fatalf("Lines(%q, %q) gives %q -> %q", a, b, older, newer)
+		}
+		equal := 0
+		for _, chunk := range chunks {
+			if chunk.Op == Equal {
+				equal += strings.Count(chunk.Text, "\n")
+			}
+		}
+		if want := lcs(splitLines(a), splitLines(b)); equal != want {
+			t.Fatalf("Lines(%q, %q) keeps %d lines, want %d", a, b, equal, want)
+		}
+	}
+}
+
+func TestTooManyEdits(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < maxEdits+10; i++ {
+		a.WriteString("a\n")
+		b.WriteString("b\n")
+	}
+	chunks := Lines(a.String(), b.String())
+	if older, newer := texts(chunks); older != a.String() || newer != b.String() {
+		t.Fatal("chunks do not add up to the texts")
+	}
+}
+
+func lcs(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}